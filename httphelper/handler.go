@@ -5,6 +5,7 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/TMSLabs/go-tooling/telemetry"
 	"github.com/getsentry/sentry-go"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
@@ -12,6 +13,12 @@ import (
 
 // HTTPHandler wraps an HTTP handler function with OpenTelemetry tracing.
 // It extracts the trace context from the HTTP request headers and starts a new span.
+// The context also carries a Sentry hub cloned for this request, which
+// telemetry.CaptureError and telemetry.AddBreadcrumb use.
+// A panic in the handler is reported through telemetry.ReportPanic and answered with a 500,
+// unless telemetry.WithRepanic is set.
+// With telemetry.WithIdentityFromBaggage, the tenant, user and request ids received as baggage
+// become span attributes and Sentry tags, see telemetry.ApplyIdentity.
 // The handler function receives a context with the trace span and the HTTP response writer and request.
 // The span name can be customized with the `spanName` parameter.
 // Example usage:
@@ -30,22 +37,38 @@ func HTTPHandler(
 	handler func(ctx context.Context, w http.ResponseWriter, r *http.Request),
 	spanName string,
 ) http.HandlerFunc {
-	propagator := otel.GetTextMapPropagator()
+	if handler == nil {
+		panic("httphelper: nil handler")
+	}
+	propagator := telemetry.Propagator()
 	tracer := otel.Tracer("httphelper")
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		sentry.AddBreadcrumb(&sentry.Breadcrumb{
+		// Each request gets its own Sentry hub so breadcrumbs don't mix between requests
+		ctx := telemetry.ContextWithHub(r.Context())
+		url := telemetry.RedactURL(r.URL)
+		telemetry.AddBreadcrumb(ctx, &sentry.Breadcrumb{
 			Category: "http.receive",
-			Message:  r.Method + " " + r.URL.String(),
+			Message:  r.Method + " " + url,
 			Data: map[string]any{
 				"method": r.Method,
-				"url":    r.URL.String(),
+				"url":    url,
 			},
 		})
 
-		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx = propagator.Extract(ctx, propagation.HeaderCarrier(r.Header))
 		ctx, span := tracer.Start(ctx, spanName)
 		defer span.End()
+		telemetry.ApplyIdentity(ctx)
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+				telemetry.ReportPanic(ctx, recovered)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
 		handler(ctx, w, r)
 	}
 }
@@ -7,7 +7,10 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/TMSLabs/go-tooling/telemetry"
+	"github.com/getsentry/sentry-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/trace"
@@ -218,3 +221,42 @@ func TestHTTPHandler_NilHandler(t *testing.T) {
 
 	wrappedHandler.ServeHTTP(w, req)
 }
+
+func TestHTTPHandler_RequestScopedHub(t *testing.T) {
+	var hubs []*sentry.Hub
+	handlerFunc := func(ctx context.Context, w http.ResponseWriter, _ *http.Request) {
+		hubs = append(hubs, telemetry.HubFromContext(ctx))
+		w.WriteHeader(http.StatusOK)
+	}
+
+	wrappedHandler := HTTPHandler(handlerFunc, "HubHandler")
+	for i := 0; i < 2; i++ {
+		wrappedHandler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	}
+
+	require.Len(t, hubs, 2)
+	assert.NotSame(t, hubs[0], hubs[1])
+	assert.NotSame(t, sentry.CurrentHub(), hubs[0])
+}
+
+func TestHTTPHandler_RecoversPanic(t *testing.T) {
+	handlerFunc := func(_ context.Context, _ http.ResponseWriter, _ *http.Request) {
+		panic("handler failed")
+	}
+
+	w := httptest.NewRecorder()
+	assert.NotPanics(t, func() {
+		HTTPHandler(handlerFunc, "PanicHandler").ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+	})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestHTTPHandler_AbortHandlerPanics(t *testing.T) {
+	handlerFunc := func(_ context.Context, _ http.ResponseWriter, _ *http.Request) {
+		panic(http.ErrAbortHandler)
+	}
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		HTTPHandler(handlerFunc, "AbortHandler").ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	})
+}
@@ -2,17 +2,22 @@ package httphelper
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"net/url"
 
+	"github.com/TMSLabs/go-tooling/telemetry"
 	"github.com/getsentry/sentry-go"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
 )
 
 // HTTPDo performs an HTTP request with OpenTelemetry tracing.
-// It injects the current trace context into the request headers and starts a new span for the request.
+// It injects the current trace context and baggage, including the ids set with telemetry.WithTenantID,
+// telemetry.WithUserID and telemetry.WithRequestID, into the request headers and starts a new span for the request.
 // The function takes a context, an HTTP client, an HTTP request, and a span name.
-// It returns the HTTP response and any error encountered.
+// It returns the HTTP response and any error encountered. The error is recorded on the span
+// according to its class, see telemetry.RecordError, with its URL redacted like the breadcrumb's.
 // It is recommended to use this function in conjunction with OpenTelemetry for distributed tracing.
 // Example usage:
 //
@@ -39,16 +44,17 @@ func HTTPDo(
 	req *http.Request,
 	spanName string,
 ) (*http.Response, error) {
-	sentry.AddBreadcrumb(&sentry.Breadcrumb{
+	reqURL := telemetry.RedactURL(req.URL)
+	telemetry.AddBreadcrumb(ctx, &sentry.Breadcrumb{
 		Category: "http.request",
-		Message:  req.Method + " " + req.URL.String(),
+		Message:  req.Method + " " + reqURL,
 		Data: map[string]any{
 			"method": req.Method,
-			"url":    req.URL.String(),
+			"url":    reqURL,
 		},
 	})
 
-	propagator := otel.GetTextMapPropagator()
+	propagator := telemetry.Propagator()
 	tracer := otel.Tracer("httphelper")
 
 	ctx, span := tracer.Start(ctx, spanName)
@@ -59,5 +65,20 @@ func HTTPDo(
 
 	// Use passed context for request
 	req = req.WithContext(ctx)
-	return client.Do(req)
+	resp, err := client.Do(req)
+	telemetry.RecordError(ctx, redactURLError(err, reqURL))
+	return resp, err
+}
+
+// redactURLError returns err with the URL of the *url.Error returned by http.Client.Do
+// replaced by reqURL, so query parameters denylisted by the redaction policy don't reach
+// the span. The error returned to the caller is left as it is.
+func redactURLError(err error, reqURL string) error {
+	var uerr *url.Error
+	if !errors.As(err, &uerr) {
+		return err
+	}
+	redacted := *uerr
+	redacted.URL = reqURL
+	return &redacted
 }
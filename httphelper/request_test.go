@@ -7,11 +7,14 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/TMSLabs/go-tooling/telemetry"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func setupTestTracer() *trace.TracerProvider {
@@ -247,3 +250,74 @@ func TestHTTPDo_NilParameters(t *testing.T) {
 		_ = resp.Body.Close()
 	}
 }
+
+func TestHTTPDo_RecordsClassifiedErrors(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := trace.NewTracerProvider(trace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	req, err := http.NewRequest("GET", "http://127.0.0.1:9999", nil)
+	require.NoError(t, err)
+	resp, err := HTTPDo(context.Background(), &http.Client{}, req, "RefusedRequest")
+	require.Error(t, err)
+	assert.Nil(t, resp)
+
+	// A caller that went away is not a failure of the request
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, err = http.NewRequest("GET", "http://127.0.0.1:9999", nil)
+	require.NoError(t, err)
+	resp, err = HTTPDo(ctx, &http.Client{}, req, "CancelledRequest")
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, resp)
+
+	ended := recorder.Ended()
+	require.Len(t, ended, 2)
+	assert.Equal(t, codes.Error, ended[0].Status().Code)
+	assert.Equal(t, codes.Unset, ended[1].Status().Code)
+}
+
+func TestHTTPDo_PropagatesIdentity(t *testing.T) {
+	var tenant, requestID string
+	server := httptest.NewServer(HTTPHandler(func(ctx context.Context, w http.ResponseWriter, _ *http.Request) {
+		tenant = telemetry.TenantID(ctx)
+		requestID = telemetry.RequestID(ctx)
+		w.WriteHeader(http.StatusOK)
+	}, "IdentityHandler"))
+	defer server.Close()
+
+	ctx := telemetry.WithTenantID(context.Background(), "acme")
+	ctx = telemetry.WithRequestID(ctx, "req-1")
+	req, err := http.NewRequest("GET", server.URL, nil)
+	require.NoError(t, err)
+	resp, err := HTTPDo(ctx, &http.Client{}, req, "IdentityRequest")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "acme", tenant)
+	assert.Equal(t, "req-1", requestID)
+}
+
+func TestHTTPDo_RedactsRecordedErrorURL(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := trace.NewTracerProvider(trace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	req, err := http.NewRequest("GET", "http://127.0.0.1:9999/x?token=supersecret", nil)
+	require.NoError(t, err)
+	resp, err := HTTPDo(context.Background(), &http.Client{}, req, "RefusedRequest")
+	require.Error(t, err)
+	assert.Nil(t, resp)
+	assert.Contains(t, err.Error(), "supersecret", "the caller's error is left as it is")
+
+	ended := recorder.Ended()
+	require.Len(t, ended, 1)
+	assert.Contains(t, ended[0].Status().Description, "token=[REDACTED]")
+	assert.NotContains(t, ended[0].Status().Description, "supersecret")
+	require.Len(t, ended[0].Events(), 1)
+	for _, attr := range ended[0].Events()[0].Attributes {
+		assert.NotContains(t, attr.Value.Emit(), "supersecret", "attribute %s", attr.Key)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
-	"time"
 
 	"github.com/TMSLabs/go-tooling/httphelper"
 	"github.com/TMSLabs/go-tooling/mysqlhelper"
@@ -34,31 +33,26 @@ func TestTelemetryMySQLIntegration(t *testing.T) {
 	// This will succeed at initialization but fail during health checks
 	if err != nil {
 		t.Logf("Expected initialization error: %v", err)
-		// If init fails, we can still test the configuration
-		assert.True(t, telemetry.TelemetryConfig.MysqlEnabled)
-		assert.Equal(
-			t,
-			"user:pass@tcp(127.0.0.1:9998)/testdb",
-			telemetry.TelemetryConfig.MysqlConfig.DSN,
-		)
+		// A failed Init leaves the previous default in place
+		assert.NotEqual(t, "integration-test-service", telemetry.Default().Config().ServiceName)
 		return
 	}
 
 	assert.NotNil(t, shutdown)
-	defer shutdown()
+	defer func() { _ = shutdown(context.Background()) }()
 
 	// Verify telemetry configuration
-	assert.True(t, telemetry.TelemetryConfig.MysqlEnabled)
-	assert.True(t, telemetry.TelemetryConfig.SlogEnabled)
-	assert.Equal(t, "integration-test-service", telemetry.TelemetryConfig.ServiceName)
+	assert.True(t, telemetry.Default().Config().MysqlEnabled)
+	assert.True(t, telemetry.Default().Config().SlogEnabled)
+	assert.Equal(t, "integration-test-service", telemetry.Default().Config().ServiceName)
 
 	// Test direct MySQL connection (should fail)
-	db, err := mysqlhelper.Connect(telemetry.TelemetryConfig.MysqlConfig.DSN)
+	db, err := mysqlhelper.Connect(telemetry.Default().Config().MysqlConfig.DSN)
 	require.Error(t, err)
 	assert.Nil(t, db)
 
 	// Test MySQL health check through telemetry (should fail)
-	err = mysqlhelper.CheckConnection(telemetry.TelemetryConfig.MysqlConfig.DSN)
+	err = mysqlhelper.CheckConnection(telemetry.Default().Config().MysqlConfig.DSN)
 	require.Error(t, err)
 
 	// Test health endpoint integration
@@ -68,7 +62,7 @@ func TestTelemetryMySQLIntegration(t *testing.T) {
 	telemetry.HealthzEndpointHandler(w, req)
 
 	// Should fail because MySQL connection fails
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 	assert.Contains(t, w.Body.String(), "MySQL connection failed")
 }
 
@@ -87,9 +81,8 @@ func TestTelemetryNATSIntegration(t *testing.T) {
 	assert.Nil(t, shutdown)
 	assert.Contains(t, err.Error(), "nats connection failed")
 
-	// Verify configuration was set even though connection failed
-	assert.True(t, telemetry.TelemetryConfig.NatsEnabled)
-	assert.Equal(t, "nats://127.0.0.1:9999", telemetry.TelemetryConfig.NatsConfig.URL)
+	// A failed Init leaves the previous default in place
+	assert.NotEqual(t, "nats-integration-test", telemetry.Default().Config().ServiceName)
 }
 
 // TestHTTPTelemetryIntegration demonstrates HTTP request tracing integration
@@ -108,7 +101,7 @@ func TestHTTPTelemetryIntegration(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.NotNil(t, shutdown)
-	defer shutdown()
+	defer func() { _ = shutdown(context.Background()) }()
 
 	// Create a test server that uses telemetry error capture
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -162,7 +155,7 @@ func TestHTTPHandlerTelemetryIntegration(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.NotNil(t, shutdown)
-	defer shutdown()
+	defer func() { _ = shutdown(context.Background()) }()
 
 	// Create a handler that uses telemetry
 	handlerFunc := func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
@@ -220,14 +213,13 @@ func TestFullIntegrationScenario(t *testing.T) {
 		// Expected due to MySQL connection failure
 		t.Logf("Expected initialization error: %v", err)
 
-		// Verify configuration was set
-		assert.True(t, telemetry.TelemetryConfig.SlogEnabled)
-		assert.True(t, telemetry.TelemetryConfig.MysqlEnabled)
+		// A failed Init leaves the previous default in place
+		assert.NotEqual(t, "full-integration-test", telemetry.Default().Config().ServiceName)
 		return
 	}
 
 	assert.NotNil(t, shutdown)
-	defer shutdown()
+	defer func() { _ = shutdown(context.Background()) }()
 
 	// Test health endpoint with multiple services
 	req := httptest.NewRequest("GET", "/healthz", nil)
@@ -236,7 +228,7 @@ func TestFullIntegrationScenario(t *testing.T) {
 	telemetry.HealthzEndpointHandler(w, req)
 
 	// Should fail due to MySQL connection
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 	assert.Contains(t, w.Body.String(), "MySQL connection failed")
 
 	// Test error capture in integration context
@@ -251,27 +243,5 @@ func TestFullIntegrationScenario(t *testing.T) {
 	// No assertion needed - just verify it doesn't panic
 }
 
-// TestIntegrationWithRealTime demonstrates time-based functionality
-func TestIntegrationWithRealTime(t *testing.T) {
-	// Test the health check event timing logic
-	originalEvent := telemetry.LastHealthCheckEvent
-	defer func() {
-		telemetry.LastHealthCheckEvent = originalEvent
-	}()
-
-	// Set a recent health check event
-	recentTime := time.Now().Add(-1 * time.Minute)
-	telemetry.LastHealthCheckEvent = recentTime.Format(time.RFC3339)
-
-	// Just verify the timestamp was set correctly
-	assert.Equal(t, recentTime.Format(time.RFC3339), telemetry.LastHealthCheckEvent)
-
-	// Test with an old timestamp
-	oldTime := time.Now().Add(-10 * time.Minute)
-	telemetry.LastHealthCheckEvent = oldTime.Format(time.RFC3339)
-
-	assert.Equal(t, oldTime.Format(time.RFC3339), telemetry.LastHealthCheckEvent)
-}
-
 // Note: The config and natsConfig types are not exported, so I need to check the actual types
 // Let me fix the type references above
@@ -6,13 +6,45 @@ import (
 	"strings"
 )
 
+// namespaceFile is mounted into every pod that has a service account token.
+var namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
 func getNamespace() (string, error) {
-	namespaceFile := "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
 	data, err := os.ReadFile(namespaceFile)
 	if err != nil {
 		return "", err
 	}
-	return string(data), nil
+	return strings.TrimSpace(string(data)), nil
+}
+
+// GetNamespace returns the namespace of the pod from the POD_NAMESPACE Downward API
+// variable or, without it, from the service account files. It returns an empty string
+// outside Kubernetes.
+func GetNamespace() string {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+	namespace, _ := getNamespace()
+	return namespace
+}
+
+// GetPodName returns the pod name from the POD_NAME Downward API variable. Inside
+// Kubernetes it falls back to HOSTNAME, which defaults to the pod name.
+// It returns an empty string outside Kubernetes.
+func GetPodName() string {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name
+	}
+	if GetNamespace() == "" {
+		return ""
+	}
+	return os.Getenv("HOSTNAME")
+}
+
+// GetNodeName returns the node name from the NODE_NAME Downward API variable, which has
+// to be set from spec.nodeName in the pod spec.
+func GetNodeName() string {
+	return os.Getenv("NODE_NAME")
 }
 
 // GetEnvironment determines the environment based on the Kubernetes namespace.
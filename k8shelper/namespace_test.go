@@ -0,0 +1,61 @@
+package k8shelper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// useNamespaceFile points the service account namespace file at a temporary file with
+// the given content for the duration of the test. An empty content removes the file.
+func useNamespaceFile(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "namespace")
+	if content != "" {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prev := namespaceFile
+	namespaceFile = path
+	t.Cleanup(func() { namespaceFile = prev })
+}
+
+func TestGetNamespace_ServiceAccountFile(t *testing.T) {
+	useNamespaceFile(t, "payments-dev\n")
+	t.Setenv("POD_NAMESPACE", "")
+
+	assert.Equal(t, "payments-dev", GetNamespace())
+	assert.Equal(t, "development", GetEnvironment())
+}
+
+func TestGetNamespace_DownwardAPIWins(t *testing.T) {
+	useNamespaceFile(t, "payments-dev")
+	t.Setenv("POD_NAMESPACE", "payments-prod")
+
+	assert.Equal(t, "payments-prod", GetNamespace())
+}
+
+func TestGetPodName(t *testing.T) {
+	useNamespaceFile(t, "payments-dev")
+	t.Setenv("POD_NAMESPACE", "")
+	t.Setenv("HOSTNAME", "orders-7d9f8-abcde")
+
+	t.Setenv("POD_NAME", "")
+	assert.Equal(t, "orders-7d9f8-abcde", GetPodName())
+
+	t.Setenv("POD_NAME", "explicit-pod")
+	assert.Equal(t, "explicit-pod", GetPodName())
+}
+
+func TestGetPodName_OutsideKubernetes(t *testing.T) {
+	useNamespaceFile(t, "")
+	t.Setenv("POD_NAMESPACE", "")
+	t.Setenv("POD_NAME", "")
+	t.Setenv("HOSTNAME", "laptop")
+
+	assert.Empty(t, GetPodName())
+	assert.Equal(t, "local", GetEnvironment())
+}
@@ -2,6 +2,7 @@
 package mysqlhelper
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -38,3 +39,13 @@ func CheckConnection(dsn string) error {
 
 	return nil
 }
+
+// CheckDB checks if an existing connection pool can reach the database.
+// Unlike CheckConnection, it does not open a new pool, so it tests the connections
+// the service actually uses.
+func CheckDB(ctx context.Context, db *sqlx.DB) error {
+	if db == nil {
+		return fmt.Errorf("no MySQL connection pool")
+	}
+	return db.PingContext(ctx)
+}
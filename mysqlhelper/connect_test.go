@@ -1,8 +1,10 @@
 package mysqlhelper
 
 import (
+	"context"
 	"testing"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -128,3 +130,15 @@ func TestCheckConnection_Integration(t *testing.T) {
 	// err := CheckConnection(validDSN)
 	// assert.NoError(t, err)
 }
+
+func TestCheckDB(t *testing.T) {
+	err := CheckDB(context.Background(), nil)
+	require.Error(t, err)
+
+	// sqlx.Open does not dial, so only the ping reaches the unreachable server
+	db, err := sqlx.Open("mysql", "user:pass@tcp(127.0.0.1:9998)/dbname")
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	assert.Error(t, CheckDB(context.Background(), db))
+}
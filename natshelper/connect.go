@@ -8,7 +8,8 @@ import (
 )
 
 var (
-	// NatsConn is the global NATS connection.
+	// NatsConn is the global NATS connection set by Connect.
+	// Services that initialize telemetry with WithNATS can use Telemetry.NATSConn instead.
 	NatsConn *nats.Conn
 )
 
@@ -3,6 +3,7 @@ package natshelper
 import (
 	"context"
 
+	"github.com/TMSLabs/go-tooling/telemetry"
 	"github.com/getsentry/sentry-go"
 	"github.com/nats-io/nats.go"
 	"go.opentelemetry.io/otel"
@@ -10,8 +11,10 @@ import (
 )
 
 // Publish publishes a message to a NATS subject with OpenTelemetry tracing.
-// It injects the trace context into the message headers.
-// The function starts a new span for the publish operation and returns any error encountered.
+// It injects the trace context and baggage, including the identity ids set with telemetry.WithTenantID
+// and friends, into the message headers.
+// The function starts a new span for the publish operation and returns any error encountered,
+// which is recorded on the span according to its class, see telemetry.RecordError.
 // It is recommended to use this function in conjunction with OpenTelemetry for distributed tracing.
 // Example usage:
 //
@@ -24,12 +27,12 @@ import (
 //	    log.Fatalf("Failed to publish message: %v", err)
 //	}
 func Publish(ctx context.Context, nc *nats.Conn, subj string, data []byte) error {
-	sentry.AddBreadcrumb(&sentry.Breadcrumb{
+	telemetry.AddBreadcrumb(ctx, &sentry.Breadcrumb{
 		Category: "nats.publish",
 		Message:  subj,
 		Data: map[string]interface{}{
 			"subject": subj,
-			"data":    string(data),
+			"data":    telemetry.RedactPayload(data),
 		},
 	})
 
@@ -45,14 +48,18 @@ func Publish(ctx context.Context, nc *nats.Conn, subj string, data []byte) error
 		Header:  nats.Header{},
 	}
 	// Inject trace context into headers
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(msg.Header))
+	telemetry.Propagator().Inject(ctx, propagation.HeaderCarrier(msg.Header))
 
-	return nc.PublishMsg(msg)
+	err := nc.PublishMsg(msg)
+	telemetry.RecordError(ctx, err)
+	return err
 }
 
 // PublishMsg publishes a NATS message with OpenTelemetry tracing.
-// It injects the trace context into the message headers.
-// The function starts a new span for the publish operation and returns any error encountered.
+// It injects the trace context and baggage, including the identity ids set with telemetry.WithTenantID
+// and friends, into the message headers.
+// The function starts a new span for the publish operation and returns any error encountered,
+// which is recorded on the span according to its class, see telemetry.RecordError.
 // It is recommended to use this function in conjunction with OpenTelemetry for distributed tracing.
 // Example usage:
 //
@@ -70,12 +77,12 @@ func Publish(ctx context.Context, nc *nats.Conn, subj string, data []byte) error
 //	    log.Fatalf("Failed to publish message: %v", err)
 //	}
 func PublishMsg(ctx context.Context, nc *nats.Conn, msg *nats.Msg) error {
-	sentry.AddBreadcrumb(&sentry.Breadcrumb{
+	telemetry.AddBreadcrumb(ctx, &sentry.Breadcrumb{
 		Category: "nats.publish",
 		Message:  msg.Subject,
 		Data: map[string]interface{}{
 			"subject": msg.Subject,
-			"data":    string(msg.Data),
+			"data":    telemetry.RedactPayload(msg.Data),
 		},
 	})
 
@@ -89,7 +96,9 @@ func PublishMsg(ctx context.Context, nc *nats.Conn, msg *nats.Msg) error {
 	if msg.Header == nil {
 		msg.Header = nats.Header{}
 	}
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(msg.Header))
+	telemetry.Propagator().Inject(ctx, propagation.HeaderCarrier(msg.Header))
 
-	return nc.PublishMsg(msg)
+	err := nc.PublishMsg(msg)
+	telemetry.RecordError(ctx, err)
+	return err
 }
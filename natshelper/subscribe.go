@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/TMSLabs/go-tooling/telemetry"
 	"github.com/getsentry/sentry-go"
 	"github.com/nats-io/nats.go"
 	"go.opentelemetry.io/otel"
@@ -12,7 +13,11 @@ import (
 
 // Subscribe subscribes to a NATS subject and processes messages with the provided handler.
 // It extracts trace context from NATS headers if present and starts a new span for message processing.
-// The handler function receives a context and the NATS message.
+// The handler function receives a context and the NATS message. The context carries a
+// Sentry hub cloned for this message, which telemetry.CaptureError and telemetry.AddBreadcrumb use.
+// A panic in the handler is reported through telemetry.Recover instead of crashing the process,
+// unless telemetry.WithRepanic is set. The identity ids received as baggage are applied with
+// telemetry.ApplyIdentity.
 // It returns the subscription and any error encountered.
 // It is recommended to use this function in conjunction with OpenTelemetry for distributed tracing.
 // Example usage:
@@ -40,30 +45,37 @@ func Subscribe(
 	tracer := otel.Tracer("natshelper")
 
 	return nc.Subscribe(subj, func(msg *nats.Msg) {
-		sentry.AddBreadcrumb(&sentry.Breadcrumb{
+		// Each message gets its own Sentry hub so breadcrumbs don't mix between messages
+		ctx := telemetry.ContextWithHub(context.Background())
+		telemetry.AddBreadcrumb(ctx, &sentry.Breadcrumb{
 			Category: "nats.receive",
 			Message:  msg.Subject,
 			Data: map[string]interface{}{
 				"subject": msg.Subject,
-				"data":    string(msg.Data),
+				"data":    telemetry.RedactPayload(msg.Data),
 			},
 		})
 
-		ctx := context.Background()
 		// Extract trace context from NATS headers if present
 		if msg.Header != nil {
-			ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(msg.Header))
+			ctx = telemetry.Propagator().Extract(ctx, propagation.HeaderCarrier(msg.Header))
 		}
 		// Start a new span for message processing
 		ctx, span := tracer.Start(ctx, fmt.Sprintf("nats.receive.%s", msg.Subject))
 		defer span.End()
+		telemetry.ApplyIdentity(ctx)
+		defer telemetry.Recover(ctx)
 		handler(ctx, msg)
 	})
 }
 
 // QueueSubscribe subscribes to a NATS subject with a queue group and processes messages with the provided handler.
 // It extracts trace context from NATS headers if present and starts a new span for message processing.
-// The handler function receives a context and the NATS message.
+// The handler function receives a context and the NATS message. The context carries a
+// Sentry hub cloned for this message, which telemetry.CaptureError and telemetry.AddBreadcrumb use.
+// A panic in the handler is reported through telemetry.Recover instead of crashing the process,
+// unless telemetry.WithRepanic is set. The identity ids received as baggage are applied with
+// telemetry.ApplyIdentity.
 // It returns the subscription and any error encountered.
 // It is recommended to use this function in conjunction with OpenTelemetry for distributed tracing.
 // Example usage:
@@ -92,23 +104,26 @@ func QueueSubscribe(
 	tracer := otel.Tracer("natshelper")
 
 	return nc.QueueSubscribe(subj, queue, func(msg *nats.Msg) {
-		sentry.AddBreadcrumb(&sentry.Breadcrumb{
+		// Each message gets its own Sentry hub so breadcrumbs don't mix between messages
+		ctx := telemetry.ContextWithHub(context.Background())
+		telemetry.AddBreadcrumb(ctx, &sentry.Breadcrumb{
 			Category: "nats.receive",
 			Message:  msg.Subject,
 			Data: map[string]interface{}{
 				"subject": msg.Subject,
-				"data":    string(msg.Data),
+				"data":    telemetry.RedactPayload(msg.Data),
 			},
 		})
 
-		ctx := context.Background()
 		// Extract trace context from NATS headers if present
 		if msg.Header != nil {
-			ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(msg.Header))
+			ctx = telemetry.Propagator().Extract(ctx, propagation.HeaderCarrier(msg.Header))
 		}
 		// Start a new span for message processing
 		ctx, span := tracer.Start(ctx, fmt.Sprintf("nats.receive.%s", msg.Subject))
 		defer span.End()
+		telemetry.ApplyIdentity(ctx)
+		defer telemetry.Recover(ctx)
 		handler(ctx, msg)
 	})
 }
@@ -0,0 +1,153 @@
+package natshelper
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TMSLabs/go-tooling/telemetry/telemetrytest"
+	"github.com/getsentry/sentry-go"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// newFakeNATSServer speaks enough of the NATS protocol for a client to connect, subscribe
+// and publish with headers, delivering each message to every subscription on its subject.
+// It returns the server URL.
+func newFakeNATSServer(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lis.Close() })
+
+	type subscription struct {
+		conn net.Conn
+		sid  string
+	}
+	var mu sync.Mutex
+	subs := map[string][]subscription{}
+
+	serve := func(conn net.Conn) {
+		defer func() { _ = conn.Close() }()
+		_, _ = io.WriteString(conn, `INFO {"server_id":"fake","version":"2.10.0","headers":true,"max_payload":1048576}`+"\r\n")
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case "PING":
+				_, _ = io.WriteString(conn, "PONG\r\n")
+			case "SUB":
+				// SUB <subject> [queue group] <sid>
+				mu.Lock()
+				subs[fields[1]] = append(subs[fields[1]], subscription{conn: conn, sid: fields[len(fields)-1]})
+				mu.Unlock()
+			case "PUB", "HPUB":
+				// PUB <subject> <size>, HPUB <subject> <header size> <total size>
+				size, _ := strconv.Atoi(fields[len(fields)-1])
+				payload := make([]byte, size+2) // With the trailing CRLF
+				if _, err := io.ReadFull(reader, payload); err != nil {
+					return
+				}
+				verb := "MSG"
+				if fields[0] == "HPUB" {
+					verb = "HMSG"
+				}
+				mu.Lock()
+				for _, sub := range subs[fields[1]] {
+					sizes := strings.Join(fields[2:], " ")
+					_, _ = fmt.Fprintf(sub.conn, "%s %s %s %s\r\n%s", verb, fields[1], sub.sid, sizes, payload)
+				}
+				mu.Unlock()
+			}
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return "nats://" + lis.Addr().String()
+}
+
+var errHandlerBug = errors.New("handler bug")
+
+func TestSubscribe_RecoversPanic(t *testing.T) {
+	tests := []struct {
+		name      string
+		subscribe func(nc *nats.Conn, handler func(ctx context.Context, msg *nats.Msg)) (*nats.Subscription, error)
+	}{
+		{
+			name: "Subscribe",
+			subscribe: func(nc *nats.Conn, handler func(ctx context.Context, msg *nats.Msg)) (*nats.Subscription, error) {
+				return Subscribe(nc, "orders", handler)
+			},
+		},
+		{
+			name: "QueueSubscribe",
+			subscribe: func(nc *nats.Conn, handler func(ctx context.Context, msg *nats.Msg)) (*nats.Subscription, error) {
+				return QueueSubscribe(nc, "orders", "workers", handler)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kit := telemetrytest.New(t)
+			nc, err := nats.Connect(newFakeNATSServer(t))
+			require.NoError(t, err)
+			defer nc.Close()
+
+			handled := make(chan string, 2)
+			sub, err := tt.subscribe(nc, func(_ context.Context, msg *nats.Msg) {
+				if string(msg.Data) == "panic" {
+					panic(errHandlerBug)
+				}
+				handled <- string(msg.Data)
+			})
+			require.NoError(t, err)
+			defer func() { _ = sub.Unsubscribe() }()
+			require.NoError(t, nc.Flush())
+
+			ctx := context.Background()
+			require.NoError(t, Publish(ctx, nc, "orders", []byte("panic")))
+			require.NoError(t, Publish(ctx, nc, "orders", []byte("order-1")))
+
+			// The subscription keeps delivering after the panic
+			select {
+			case data := <-handled:
+				assert.Equal(t, "order-1", data)
+			case <-time.After(5 * time.Second):
+				t.Fatal("message after the panic was not handled")
+			}
+
+			event := kit.AssertCapturedError(errHandlerBug)
+			assert.Equal(t, sentry.LevelFatal, event.Level)
+
+			receive := kit.AssertSpanParent("nats.receive.orders", "nats.publish.orders")
+			assert.Equal(t, codes.Error, receive.Status.Code)
+			assert.Equal(t, errHandlerBug.Error(), receive.Status.Description)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/getsentry/sentry-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Baggage keys of the identity helpers. WithIdentityFromBaggage uses the same keys for span
+// attributes, log attributes and Sentry tags.
+const (
+	BaggageTenantID  = "tenant.id"
+	BaggageUserID    = "user.id"
+	BaggageRequestID = "request.id"
+)
+
+// identityKeys lists the baggage keys copied by ApplyIdentity and the log handler.
+var identityKeys = []string{BaggageTenantID, BaggageUserID, BaggageRequestID}
+
+// WithTenantID returns a copy of ctx with the tenant id set as baggage, so it travels with
+// httphelper.HTTPDo and natshelper.Publish to downstream services.
+func WithTenantID(ctx context.Context, id string) context.Context {
+	return withBaggage(ctx, BaggageTenantID, id)
+}
+
+// TenantID returns the tenant id from the baggage of ctx, or "".
+func TenantID(ctx context.Context) string {
+	return baggage.FromContext(ctx).Member(BaggageTenantID).Value()
+}
+
+// WithUserID returns a copy of ctx with the user id set as baggage. See WithTenantID.
+func WithUserID(ctx context.Context, id string) context.Context {
+	return withBaggage(ctx, BaggageUserID, id)
+}
+
+// UserID returns the user id from the baggage of ctx, or "".
+func UserID(ctx context.Context) string {
+	return baggage.FromContext(ctx).Member(BaggageUserID).Value()
+}
+
+// WithRequestID returns a copy of ctx with the request id set as baggage. See WithTenantID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return withBaggage(ctx, BaggageRequestID, id)
+}
+
+// RequestID returns the request id from the baggage of ctx, or "".
+func RequestID(ctx context.Context) string {
+	return baggage.FromContext(ctx).Member(BaggageRequestID).Value()
+}
+
+// withBaggage sets key to value in the baggage of ctx. An empty value removes the key.
+// ctx is returned unchanged if the value can't be encoded.
+func withBaggage(ctx context.Context, key string, value string) context.Context {
+	bag := baggage.FromContext(ctx)
+	if value == "" {
+		return baggage.ContextWithBaggage(ctx, bag.DeleteMember(key))
+	}
+	member, err := baggage.NewMemberRaw(key, value)
+	if err != nil {
+		return ctx
+	}
+	bag, err = bag.SetMember(member)
+	if err != nil {
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// identity returns the identity members set in the baggage of ctx.
+func identity(ctx context.Context) map[string]string {
+	bag := baggage.FromContext(ctx)
+	var ids map[string]string
+	for _, key := range identityKeys {
+		if value := bag.Member(key).Value(); value != "" {
+			if ids == nil {
+				ids = map[string]string{}
+			}
+			ids[key] = value
+		}
+	}
+	return ids
+}
+
+// Propagator returns the global OpenTelemetry propagator, with W3C baggage added when it
+// doesn't handle baggage already, so the identity helpers work even when Init installed no
+// tracing. The propagator installed by Init is returned as is: a second baggage propagator
+// would overwrite the header with Sentry's dynamic sampling members merged in. The helpers
+// in httphelper and natshelper use it to inject and extract headers.
+func Propagator() propagation.TextMapPropagator {
+	global := otel.GetTextMapPropagator()
+	if slices.Contains(global.Fields(), "baggage") {
+		return global
+	}
+	return propagation.NewCompositeTextMapPropagator(global, propagation.Baggage{})
+}
+
+// WithIdentityFromBaggage copies the tenant, user and request ids received as baggage onto
+// the receiving side: ApplyIdentity sets them as span attributes and Sentry tags, and the
+// slog handler adds them to every record logged with the context.
+func WithIdentityFromBaggage() Option {
+	return func(cfg *Config) { cfg.IdentityFromBaggage = true }
+}
+
+// ApplyIdentity sets the identity ids in the baggage of ctx as attributes of the current
+// span and as tags of the Sentry hub of ctx, if the default instance was configured with
+// WithIdentityFromBaggage. httphelper.HTTPHandler and the natshelper subscribe wrappers
+// call it for every request and message.
+func ApplyIdentity(ctx context.Context) {
+	Default().ApplyIdentity(ctx)
+}
+
+// ApplyIdentity applies the identity of ctx according to the configuration of t. See ApplyIdentity.
+func (t *Telemetry) ApplyIdentity(ctx context.Context) {
+	if !t.cfg.IdentityFromBaggage {
+		return
+	}
+	ids := identity(ctx)
+	if len(ids) == 0 {
+		return
+	}
+	attrs := make([]attribute.KeyValue, 0, len(ids))
+	for key, value := range ids {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+	// Only a request-scoped hub, never the global one
+	if hub := sentry.GetHubFromContext(ctx); hub != nil {
+		hub.Scope().SetTags(ids)
+	}
+}
+
+// identityAttrs returns the identity of ctx as slog attributes in a fixed order.
+func identityAttrs(ctx context.Context) []slog.Attr {
+	bag := baggage.FromContext(ctx)
+	var attrs []slog.Attr
+	for _, key := range identityKeys {
+		if value := bag.Member(key).Value(); value != "" {
+			attrs = append(attrs, slog.String(key, value))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,114 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestIdentityBaggage(t *testing.T) {
+	ctx := WithTenantID(context.Background(), "acme")
+	ctx = WithUserID(ctx, "user 7") // Values are percent-encoded on the wire
+	ctx = WithRequestID(ctx, "req-1")
+
+	assert.Equal(t, "acme", TenantID(ctx))
+	assert.Equal(t, "user 7", UserID(ctx))
+	assert.Equal(t, "req-1", RequestID(ctx))
+
+	ctx = WithUserID(ctx, "")
+	assert.Empty(t, UserID(ctx))
+	assert.Equal(t, map[string]string{BaggageTenantID: "acme", BaggageRequestID: "req-1"}, identity(ctx))
+	assert.Nil(t, identity(context.Background()))
+}
+
+func TestPropagator_CarriesIdentity(t *testing.T) {
+	ctx := WithTenantID(context.Background(), "acme")
+	ctx = WithUserID(ctx, "user 7")
+
+	header := http.Header{}
+	Propagator().Inject(ctx, propagation.HeaderCarrier(header))
+	require.NotEmpty(t, header.Get("baggage"))
+
+	received := Propagator().Extract(context.Background(), propagation.HeaderCarrier(header))
+	assert.Equal(t, "acme", TenantID(received))
+	assert.Equal(t, "user 7", UserID(received))
+}
+
+func TestPropagator_KeepsSentryBaggage(t *testing.T) {
+	shutdown, err := Init("baggage-test", "test",
+		WithSentry(SentryDSN("https://abc@o123456.ingest.us.sentry.io/123456")),
+		WithTrace(TraceSpanProcessor(sdktrace.NewSimpleSpanProcessor(tracetest.NewInMemoryExporter()))),
+	)
+	require.NoError(t, err)
+	defer func() { _ = shutdown(context.Background()) }()
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "baggage-span")
+	defer span.End()
+	ctx = WithTenantID(ctx, "acme")
+
+	carrier := propagation.MapCarrier{}
+	Propagator().Inject(ctx, carrier)
+
+	// The Sentry dynamic sampling context of the span survives next to the identity
+	out, err := baggage.Parse(carrier.Get("baggage"))
+	require.NoError(t, err)
+	assert.Equal(t, "acme", out.Member(BaggageTenantID).Value())
+	assert.Equal(t, span.SpanContext().TraceID().String(), out.Member("sentry-trace_id").Value())
+	assert.Equal(t, "abc", out.Member("sentry-public_key").Value())
+}
+
+func TestApplyIdentity(t *testing.T) {
+	events := recordSentryEvents(t)
+	useDefault(t, Config{SentryEnabled: true, IdentityFromBaggage: true})
+
+	ctx, spans, span := recordSpans(t)
+	ctx = ContextWithHub(WithTenantID(ctx, "acme"))
+	ApplyIdentity(ctx)
+	CaptureMessage(ctx, "order placed")
+	span.End()
+
+	require.Len(t, spans.Ended(), 1)
+	assert.Contains(t, spans.Ended()[0].Attributes(), attribute.String(BaggageTenantID, "acme"))
+	require.Len(t, events.all(), 1)
+	assert.Equal(t, "acme", events.all()[0].Tags[BaggageTenantID])
+
+	// Without a request-scoped hub the global scope is left alone
+	ApplyIdentity(WithTenantID(context.Background(), "other"))
+	CaptureMessage(context.Background(), "global")
+	require.Len(t, events.all(), 2)
+	assert.NotContains(t, events.all()[1].Tags, BaggageTenantID)
+}
+
+func TestApplyIdentity_Disabled(t *testing.T) {
+	useDefault(t, Config{})
+
+	ctx, spans, span := recordSpans(t)
+	ApplyIdentity(WithTenantID(ctx, "acme"))
+	span.End()
+
+	require.Len(t, spans.Ended(), 1)
+	assert.Empty(t, spans.Ended()[0].Attributes())
+}
+
+func TestOTelHandler_Identity(t *testing.T) {
+	var buf bytes.Buffer
+	handler := newOTelHandler(slog.NewTextHandler(&buf, nil))
+	handler.identity = true
+	logger := slog.New(handler).With("component", "orders")
+
+	ctx := WithRequestID(WithTenantID(context.Background(), "acme"), "req-1")
+	logger.InfoContext(ctx, "order placed")
+
+	assert.Contains(t, buf.String(), "tenant.id=acme request.id=req-1")
+}
@@ -2,7 +2,9 @@ package telemetry
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"maps"
 
 	"github.com/getsentry/sentry-go"
 	"go.opentelemetry.io/otel/attribute"
@@ -10,57 +12,335 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+// CaptureOption defines a function type for adding context to a captured error or message.
+type CaptureOption func(*captureConfig)
+
+type captureConfig struct {
+	tags        map[string]string
+	extras      map[string]any
+	fingerprint []string
+	level       sentry.Level
+	user        *sentry.User
+	class       string
+	suppressed  int // Events dropped by the limiter since the last one that got through
+}
+
+func newCaptureConfig(level sentry.Level, opts []CaptureOption) *captureConfig {
+	cfg := &captureConfig{level: level}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// CaptureTag adds a searchable Sentry tag and a span attribute, e.g. an order id or tenant.
+func CaptureTag(key string, value string) CaptureOption {
+	return func(cfg *captureConfig) {
+		if cfg.tags == nil {
+			cfg.tags = map[string]string{}
+		}
+		cfg.tags[key] = value
+	}
+}
+
+// CaptureTags adds several tags at once. See CaptureTag.
+func CaptureTags(tags map[string]string) CaptureOption {
+	return func(cfg *captureConfig) {
+		if cfg.tags == nil {
+			cfg.tags = map[string]string{}
+		}
+		maps.Copy(cfg.tags, tags)
+	}
+}
+
+// CaptureExtra attaches unindexed data to the Sentry event. On the span it becomes the
+// attribute "error.extra.<key>" with value formatted by fmt.Sprint.
+func CaptureExtra(key string, value any) CaptureOption {
+	return func(cfg *captureConfig) {
+		if cfg.extras == nil {
+			cfg.extras = map[string]any{}
+		}
+		cfg.extras[key] = value
+	}
+}
+
+// CaptureFingerprint overrides how Sentry groups the event into issues.
+// Use "{{ default }}" as one of the parts to extend the default grouping.
+func CaptureFingerprint(parts ...string) CaptureOption {
+	return func(cfg *captureConfig) { cfg.fingerprint = parts }
+}
+
+// CaptureLevel sets the severity of the event. CaptureError defaults to sentry.LevelError
+// and CaptureMessage to sentry.LevelInfo. Only errors and fatal events mark the span as failed.
+func CaptureLevel(level sentry.Level) CaptureOption {
+	return func(cfg *captureConfig) { cfg.level = level }
+}
+
+// CaptureUser sets the user affected by the event. Its ID is also recorded as the
+// "enduser.id" span attribute.
+func CaptureUser(user sentry.User) CaptureOption {
+	return func(cfg *captureConfig) { cfg.user = &user }
+}
+
+// redact masks the tags and extras with denylisted keys and truncates long string values.
+// See WithRedaction.
+func (cfg *captureConfig) redact(r *redactor) {
+	for k, v := range cfg.tags {
+		cfg.tags[k] = r.value(k, v).(string)
+	}
+	for k, v := range cfg.extras {
+		cfg.extras[k] = r.value(k, v)
+	}
+}
+
+// applyScope copies the options onto a Sentry scope.
+func (cfg *captureConfig) applyScope(scope *sentry.Scope) {
+	scope.SetLevel(cfg.level)
+	if cfg.class != "" {
+		scope.SetTag("error.class", cfg.class)
+	}
+	if len(cfg.tags) > 0 {
+		scope.SetTags(cfg.tags)
+	}
+	if len(cfg.extras) > 0 {
+		scope.SetExtras(cfg.extras)
+	}
+	if len(cfg.fingerprint) > 0 {
+		scope.SetFingerprint(cfg.fingerprint)
+	}
+	if cfg.user != nil {
+		scope.SetUser(*cfg.user)
+	}
+	if cfg.suppressed > 0 {
+		scope.SetExtra("suppressed_events", cfg.suppressed)
+	}
+}
+
+// spanAttributes returns the options as span attributes.
+func (cfg *captureConfig) spanAttributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attribute.String("error.level", string(cfg.level))}
+	if cfg.class != "" {
+		attrs = append(attrs, attribute.String("error.class", cfg.class))
+	}
+	for k, v := range cfg.tags {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	for k, v := range cfg.extras {
+		attrs = append(attrs, attribute.String("error.extra."+k, fmt.Sprint(v)))
+	}
+	if len(cfg.fingerprint) > 0 {
+		attrs = append(attrs, attribute.StringSlice("error.fingerprint", cfg.fingerprint))
+	}
+	if cfg.user != nil && cfg.user.ID != "" {
+		attrs = append(attrs, attribute.String("enduser.id", cfg.user.ID))
+	}
+	if cfg.suppressed > 0 {
+		attrs = append(attrs, attribute.Int("error.suppressed_events", cfg.suppressed))
+	}
+	return attrs
+}
+
+// logArgs returns the tags and extras as slog arguments.
+func (cfg *captureConfig) logArgs() []any {
+	var args []any
+	if cfg.class != "" {
+		args = append(args, "error_class", cfg.class)
+	}
+	if len(cfg.tags) > 0 {
+		args = append(args, "tags", cfg.tags)
+	}
+	if len(cfg.extras) > 0 {
+		args = append(args, "extras", cfg.extras)
+	}
+	if cfg.suppressed > 0 {
+		args = append(args, "suppressed_events", cfg.suppressed)
+	}
+	return args
+}
+
+// isError reports whether the level marks the span as failed.
+func (cfg *captureConfig) isError() bool {
+	return cfg.level == sentry.LevelError || cfg.level == sentry.LevelFatal
+}
+
+// slogLevel maps the Sentry level to the slog level used for the log line.
+func (cfg *captureConfig) slogLevel() slog.Level {
+	switch cfg.level {
+	case sentry.LevelDebug:
+		return slog.LevelDebug
+	case sentry.LevelInfo:
+		return slog.LevelInfo
+	case sentry.LevelWarning:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
 // CaptureError captures an error in the context of telemetry.
 // It sends the error to Sentry if enabled, and records it in the current OpenTelemetry span.
 // If the error is nil, it does nothing.
 // This function is useful for logging errors in a consistent way across your application.
 // It is recommended to use this function in conjunction with OpenTelemetry for distributed tracing.
+// Options add tags, extra data, a fingerprint, a severity level or the affected user to the
+// Sentry event and the span, without touching the global Sentry scope.
+// Errors matching a class registered with RegisterErrorClass are handled according to its
+// action: context.Canceled and sql.ErrNoRows are only logged, nats.ErrTimeout is captured
+// as a warning.
 // Example usage:
 //
 //	ctx := context.Background()
 //	err := someFunctionThatMightFail()
 //
 //	if err != nil {
-//	    telemetry.CaptureError(ctx, err, "An error occurred in someFunctionThatMightFail")
+//	    telemetry.CaptureError(ctx, err, "An error occurred in someFunctionThatMightFail",
+//	        telemetry.CaptureTag("order_id", orderID),
+//	        telemetry.CaptureExtra("attempt", attempt),
+//	    )
 //	}
-func CaptureError(ctx context.Context, err error, message string) {
+func CaptureError(ctx context.Context, err error, message string, opts ...CaptureOption) {
+	Default().CaptureError(ctx, err, message, opts...)
+}
+
+// CaptureError captures err according to the configuration of t. See CaptureError.
+func (t *Telemetry) CaptureError(ctx context.Context, err error, message string, opts ...CaptureOption) {
 	if err == nil {
 		return
 	}
+	class, action := t.ClassifyError(err)
+	switch action {
+	case ErrorActionIgnore:
+		return
+	case ErrorActionLog:
+		t.Logger().InfoContext(ctx, "Error captured", "error", err, "message", message, "error_class", class)
+		return
+	}
+
+	defaultLevel := sentry.LevelError
+	if action == ErrorActionWarn {
+		defaultLevel = sentry.LevelWarning
+	}
+	cfg := newCaptureConfig(defaultLevel, opts)
+	cfg.class = class
+	cfg.redact(t.redact)
+	logger := t.Logger()
+	level := cfg.slogLevel()
+
+	// Sentry and the log only get the events the limiter lets through
+	send, suppressed := t.limiter.allow(cfg.limitKeys(message, err.Error()))
+	cfg.suppressed = suppressed
 
 	// Capture the error using Sentry
-	if TelemetryConfig.SentryEnabled {
-		slog.Error("Sentry error capture", "error", err, "message", message)
-		sentry.AddBreadcrumb(&sentry.Breadcrumb{
-			Category: "error",
-			Message:  message,
-			Data: map[string]any{
-				"error":   err.Error(),
-				"message": message,
-			},
-			Level: sentry.LevelError,
-		})
-
-		sentry.CaptureException(err)
+	if t.cfg.SentryEnabled {
+		if send {
+			logger.Log(ctx, level, "Sentry error capture", "error", err, "message", message)
+			hub := HubFromContext(ctx).Clone()
+			hub.ConfigureScope(cfg.applyScope)
+			hub.AddBreadcrumb(&sentry.Breadcrumb{
+				Category: "error",
+				Message:  message,
+				Data: map[string]any{
+					"error":   err.Error(),
+					"message": message,
+				},
+				Level: cfg.level,
+			}, nil)
+
+			hub.CaptureException(err)
+		}
 
 		sentrySpan := sentry.SpanFromContext(ctx)
-		if sentrySpan != nil {
+		if sentrySpan != nil && cfg.isError() {
 			sentrySpan.Status = sentry.SpanStatusInternalError
 		}
 	}
 
 	// If OpenTelemetry is enabled, record the error in the current span
-	if TelemetryConfig.TraceEnabled {
-		slog.Error("OpenTelemetry error capture", "error", err, "message", message)
+	if t.cfg.TraceEnabled {
+		if send {
+			logger.Log(ctx, level, "OpenTelemetry error capture", "error", err, "message", message)
+		}
 		span := trace.SpanFromContext(ctx)
 		span.SetAttributes(
 			attribute.String("error.message", err.Error()),
 			attribute.String("error.description", message),
 		)
+		span.SetAttributes(cfg.spanAttributes()...)
 		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
+		if cfg.isError() {
+			span.SetStatus(codes.Error, err.Error())
+		}
 	}
 
-	slog.Error("Error captured", "error", err, "message", message)
+	if send {
+		logger.Log(ctx, level, "Error captured", append([]any{"error", err, "message", message}, cfg.logArgs()...)...)
+	}
+}
 
+// CaptureMessage sends a non-error event to Sentry if enabled and adds it as an event to
+// the current OpenTelemetry span. It takes the same options as CaptureError and defaults
+// to sentry.LevelInfo:
+//
+//	telemetry.CaptureMessage(ctx, "Payment provider fell back to secondary region",
+//	    telemetry.CaptureLevel(sentry.LevelWarning),
+//	    telemetry.CaptureTag("provider", "stripe"),
+//	)
+func CaptureMessage(ctx context.Context, message string, opts ...CaptureOption) {
+	Default().CaptureMessage(ctx, message, opts...)
+}
+
+// CaptureMessage captures message according to the configuration of t. See CaptureMessage.
+func (t *Telemetry) CaptureMessage(ctx context.Context, message string, opts ...CaptureOption) {
+	cfg := newCaptureConfig(sentry.LevelInfo, opts)
+	cfg.redact(t.redact)
+	send, suppressed := t.limiter.allow(cfg.limitKeys(message, message))
+	cfg.suppressed = suppressed
+
+	if send && t.cfg.SentryEnabled {
+		hub := HubFromContext(ctx).Clone()
+		hub.ConfigureScope(cfg.applyScope)
+		hub.CaptureMessage(message)
+	}
+
+	if t.cfg.TraceEnabled {
+		span := trace.SpanFromContext(ctx)
+		span.AddEvent(message, trace.WithAttributes(cfg.spanAttributes()...))
+		if cfg.isError() {
+			span.SetStatus(codes.Error, message)
+		}
+	}
+
+	if send {
+		t.Logger().Log(ctx, cfg.slogLevel(), "Message captured", append([]any{"message", message}, cfg.logArgs()...)...)
+	}
+}
+
+// RecordError records err on the current OpenTelemetry span according to its class, without
+// sending it to Sentry or logging it. Errors of capture classes fail the span, warnings are
+// recorded without failing it, and ignored or log-only errors leave it untouched.
+// httphelper.HTTPDo and the natshelper publish functions use it for the errors they
+// return, leaving the decision to capture them to the caller.
+func RecordError(ctx context.Context, err error) {
+	Default().RecordError(ctx, err)
+}
+
+// RecordError records err with the error classes of t. See RecordError.
+func (t *Telemetry) RecordError(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	class, action := t.ClassifyError(err)
+	if action == ErrorActionIgnore || action == ErrorActionLog {
+		return
+	}
+
+	var attrs []attribute.KeyValue
+	if class != "" {
+		attrs = append(attrs, attribute.String("error.class", class))
+	}
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err, trace.WithAttributes(attrs...))
+	if action == ErrorActionCapture {
+		span.SetStatus(codes.Error, err.Error())
+	}
 }
@@ -3,15 +3,23 @@ package telemetry
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
-func TestCaptureError_NilError(_ *testing.T) {
+func TestCaptureError_NilError(t *testing.T) {
 	// Reset config to ensure clean state
-	TelemetryConfig = config{}
+	useDefault(t, Config{})
 
 	ctx := context.Background()
 
@@ -21,9 +29,9 @@ func TestCaptureError_NilError(_ *testing.T) {
 	// Test passes if no panic occurs
 }
 
-func TestCaptureError_WithError_NoTelemetryEnabled(_ *testing.T) {
+func TestCaptureError_WithError_NoTelemetryEnabled(t *testing.T) {
 	// Reset config to disable all telemetry
-	TelemetryConfig = config{}
+	useDefault(t, Config{})
 
 	ctx := context.Background()
 	testErr := errors.New("test error")
@@ -34,11 +42,11 @@ func TestCaptureError_WithError_NoTelemetryEnabled(_ *testing.T) {
 	// Test passes if no panic occurs
 }
 
-func TestCaptureError_WithSentryEnabled(_ *testing.T) {
+func TestCaptureError_WithSentryEnabled(t *testing.T) {
 	// Configure with Sentry enabled (but not actually initialized to avoid network calls)
-	TelemetryConfig = config{
+	useDefault(t, Config{
 		SentryEnabled: true,
-	}
+	})
 
 	ctx := context.Background()
 	testErr := errors.New("test sentry error")
@@ -49,16 +57,16 @@ func TestCaptureError_WithSentryEnabled(_ *testing.T) {
 	// Test passes if no panic occurs
 }
 
-func TestCaptureError_WithTraceEnabled(_ *testing.T) {
+func TestCaptureError_WithTraceEnabled(t *testing.T) {
 	// Set up OpenTelemetry tracer for testing
 	tp := trace.NewTracerProvider()
 	otel.SetTracerProvider(tp)
 	defer func() { _ = tp.Shutdown(context.Background()) }()
 
 	// Configure with tracing enabled
-	TelemetryConfig = config{
+	useDefault(t, Config{
 		TraceEnabled: true,
-	}
+	})
 
 	// Create a context with an active span
 	tracer := otel.Tracer("test")
@@ -74,17 +82,17 @@ func TestCaptureError_WithTraceEnabled(_ *testing.T) {
 	// Note: In a real test, you'd want to export spans to verify they were recorded correctly
 }
 
-func TestCaptureError_WithBothSentryAndTraceEnabled(_ *testing.T) {
+func TestCaptureError_WithBothSentryAndTraceEnabled(t *testing.T) {
 	// Set up OpenTelemetry tracer
 	tp := trace.NewTracerProvider()
 	otel.SetTracerProvider(tp)
 	defer func() { _ = tp.Shutdown(context.Background()) }()
 
 	// Configure with both Sentry and tracing enabled
-	TelemetryConfig = config{
+	useDefault(t, Config{
 		SentryEnabled: true,
 		TraceEnabled:  true,
-	}
+	})
 
 	// Create a context with an active span
 	tracer := otel.Tracer("test")
@@ -105,9 +113,9 @@ func TestCaptureError_DifferentErrorTypes(t *testing.T) {
 	otel.SetTracerProvider(tp)
 	defer func() { _ = tp.Shutdown(context.Background()) }()
 
-	TelemetryConfig = config{
+	useDefault(t, Config{
 		TraceEnabled: true,
-	}
+	})
 
 	tracer := otel.Tracer("test")
 	ctx, span := tracer.Start(context.Background(), "error-types-test")
@@ -154,9 +162,9 @@ func TestCaptureError_ContextVariations(t *testing.T) {
 	otel.SetTracerProvider(tp)
 	defer func() { _ = tp.Shutdown(context.Background()) }()
 
-	TelemetryConfig = config{
+	useDefault(t, Config{
 		TraceEnabled: true,
-	}
+	})
 
 	testErr := errors.New("context test error")
 
@@ -192,15 +200,15 @@ func TestCaptureError_ContextVariations(t *testing.T) {
 	}
 }
 
-func TestCaptureError_ConcurrentCalls(_ *testing.T) {
+func TestCaptureError_ConcurrentCalls(t *testing.T) {
 	tp := trace.NewTracerProvider()
 	otel.SetTracerProvider(tp)
 	defer func() { _ = tp.Shutdown(context.Background()) }()
 
-	TelemetryConfig = config{
+	useDefault(t, Config{
 		TraceEnabled:  true,
 		SentryEnabled: true,
-	}
+	})
 
 	// Test concurrent calls to CaptureError
 	const numGoroutines = 10
@@ -235,7 +243,7 @@ func TestCaptureError_ConcurrentCalls(_ *testing.T) {
 }
 
 func TestCaptureError_MessageFormatting(t *testing.T) {
-	TelemetryConfig = config{} // No telemetry enabled for simplicity
+	useDefault(t, Config{}) // No telemetry enabled for simplicity
 
 	ctx := context.Background()
 	testErr := errors.New("formatting test error")
@@ -264,9 +272,9 @@ func TestCaptureError_ErrorMessageExtraction(t *testing.T) {
 	otel.SetTracerProvider(tp)
 	defer func() { _ = tp.Shutdown(context.Background()) }()
 
-	TelemetryConfig = config{
+	useDefault(t, Config{
 		TraceEnabled: true,
-	}
+	})
 
 	tracer := otel.Tracer("test")
 	ctx, span := tracer.Start(context.Background(), "error-extraction-test")
@@ -295,3 +303,139 @@ func minInt(a, b int) int {
 	}
 	return b
 }
+
+// sentryRecorder is a Sentry transport that keeps the events instead of sending them.
+type sentryRecorder struct {
+	mu     sync.Mutex
+	events []*sentry.Event
+}
+
+func (r *sentryRecorder) Flush(_ time.Duration) bool              { return true }
+func (r *sentryRecorder) FlushWithContext(_ context.Context) bool { return true }
+func (r *sentryRecorder) Configure(_ sentry.ClientOptions)        {}
+func (r *sentryRecorder) Close()                                  {}
+
+func (r *sentryRecorder) SendEvent(event *sentry.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *sentryRecorder) all() []*sentry.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*sentry.Event(nil), r.events...)
+}
+
+// recordSentryEvents binds a client with a sentryRecorder transport to the current hub
+// for the duration of the test.
+func recordSentryEvents(t *testing.T) *sentryRecorder {
+	t.Helper()
+	recorder := &sentryRecorder{}
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:       "https://public@example.com/1",
+		Transport: recorder,
+	})
+	require.NoError(t, err)
+	hub := sentry.CurrentHub()
+	prev := hub.Client()
+	hub.BindClient(client)
+	t.Cleanup(func() { hub.BindClient(prev) })
+	return recorder
+}
+
+// recordSpans installs a span recorder on a fresh tracer provider and returns a context
+// with an active span from it.
+func recordSpans(t *testing.T) (context.Context, *tracetest.SpanRecorder, oteltrace.Span) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tp := trace.NewTracerProvider(trace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	ctx, span := tp.Tracer("test").Start(context.Background(), "capture-test")
+	return ctx, recorder, span
+}
+
+func TestCaptureError_Options(t *testing.T) {
+	events := recordSentryEvents(t)
+	ctx, spans, span := recordSpans(t)
+	useDefault(t, Config{SentryEnabled: true, TraceEnabled: true})
+
+	CaptureError(ctx, errors.New("payment declined"), "charging order failed",
+		CaptureTag("order_id", "ord-42"),
+		CaptureTags(map[string]string{"tenant": "acme"}),
+		CaptureExtra("attempt", 3),
+		CaptureFingerprint("payment-declined", "{{ default }}"),
+		CaptureUser(sentry.User{ID: "user-7"}),
+	)
+	span.End()
+
+	require.Len(t, events.all(), 1)
+	event := events.all()[0]
+	assert.Equal(t, sentry.LevelError, event.Level)
+	assert.Equal(t, "ord-42", event.Tags["order_id"])
+	assert.Equal(t, "acme", event.Tags["tenant"])
+	assert.Equal(t, 3, event.Extra["attempt"])
+	assert.Equal(t, []string{"payment-declined", "{{ default }}"}, event.Fingerprint)
+	assert.Equal(t, "user-7", event.User.ID)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, codes.Error, ended[0].Status().Code)
+	attrs := map[string]string{}
+	for _, kv := range ended[0].Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	assert.Equal(t, "ord-42", attrs["order_id"])
+	assert.Equal(t, "acme", attrs["tenant"])
+	assert.Equal(t, "3", attrs["error.extra.attempt"])
+	assert.Equal(t, "user-7", attrs["enduser.id"])
+	assert.Equal(t, "error", attrs["error.level"])
+}
+
+func TestCaptureError_OptionsDoNotLeakIntoGlobalScope(t *testing.T) {
+	events := recordSentryEvents(t)
+	useDefault(t, Config{SentryEnabled: true})
+
+	CaptureError(context.Background(), errors.New("first"), "first", CaptureTag("order_id", "ord-42"))
+	CaptureError(context.Background(), errors.New("second"), "second")
+
+	require.Len(t, events.all(), 2)
+	assert.NotContains(t, events.all()[1].Tags, "order_id")
+}
+
+func TestCaptureError_WarningLevelKeepsSpanStatus(t *testing.T) {
+	events := recordSentryEvents(t)
+	ctx, spans, span := recordSpans(t)
+	useDefault(t, Config{SentryEnabled: true, TraceEnabled: true})
+
+	CaptureError(ctx, errors.New("retrying"), "upstream slow", CaptureLevel(sentry.LevelWarning))
+	span.End()
+
+	require.Len(t, events.all(), 1)
+	assert.Equal(t, sentry.LevelWarning, events.all()[0].Level)
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, codes.Unset, ended[0].Status().Code)
+	require.Len(t, ended[0].Events(), 1) // The error is still recorded
+}
+
+func TestCaptureMessage(t *testing.T) {
+	events := recordSentryEvents(t)
+	ctx, spans, span := recordSpans(t)
+	useDefault(t, Config{SentryEnabled: true, TraceEnabled: true})
+
+	CaptureMessage(ctx, "fell back to secondary region", CaptureTag("provider", "stripe"))
+	span.End()
+
+	require.Len(t, events.all(), 1)
+	event := events.all()[0]
+	assert.Equal(t, "fell back to secondary region", event.Message)
+	assert.Equal(t, sentry.LevelInfo, event.Level)
+	assert.Equal(t, "stripe", event.Tags["provider"])
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	require.Len(t, ended[0].Events(), 1)
+	assert.Equal(t, "fell back to secondary region", ended[0].Events()[0].Name)
+	assert.Equal(t, codes.Unset, ended[0].Status().Code)
+}
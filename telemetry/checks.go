@@ -0,0 +1,278 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// defaultCheckTimeout bounds a check registered without CheckTimeout.
+const defaultCheckTimeout = 5 * time.Second
+
+// Checker reports whether a dependency of the service is healthy.
+// Check should honor the deadline of ctx; checks that don't are abandoned when it passes.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// DetailsChecker is implemented by checkers that add details, such as a round-trip time,
+// to their result. CheckDetails is called instead of Check.
+type DetailsChecker interface {
+	Checker
+	CheckDetails(ctx context.Context) (map[string]any, error)
+}
+
+// CheckerFunc adapts a function to the Checker interface.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx).
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// CheckOption defines a function type for configuring a registered check.
+type CheckOption func(*checkConfig)
+
+type checkConfig struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+	critical bool
+	tags     []string
+	probes   []Probe
+}
+
+// CheckTimeout sets how long the check may run before it counts as failed. Defaults to 5s.
+func CheckTimeout(timeout time.Duration) CheckOption {
+	return func(cfg *checkConfig) { cfg.timeout = timeout }
+}
+
+// CheckCacheTTL reuses the last result of the check for ttl, so probe storms don't hammer
+// the dependency. Overrides WithHealthCheckCacheTTL for this check.
+func CheckCacheTTL(ttl time.Duration) CheckOption {
+	return func(cfg *checkConfig) { cfg.cacheTTL = ttl }
+}
+
+// CheckNonCritical marks the check as non-critical. A failing non-critical check shows up
+// in the report and degrades the overall status, but the endpoint still answers 200.
+func CheckNonCritical() CheckOption {
+	return func(cfg *checkConfig) { cfg.critical = false }
+}
+
+// CheckTags attaches tags to the check, e.g. "database" or "external". They are included in
+// the report.
+func CheckTags(tags ...string) CheckOption {
+	return func(cfg *checkConfig) { cfg.tags = append(cfg.tags, tags...) }
+}
+
+// CheckProbes sets the Kubernetes probes the check participates in. Without it, a check
+// belongs to the readiness and startup probes, so a failing dependency takes the pod out
+// of rotation instead of restarting it. Only checks that list ProbeLiveness fail livez.
+func CheckProbes(probes ...Probe) CheckOption {
+	return func(cfg *checkConfig) { cfg.probes = probes }
+}
+
+type registeredCheck struct {
+	name    string
+	checker Checker
+	checkConfig
+
+	// mu serializes runs so concurrent probes share one result while it is cached
+	mu       sync.Mutex
+	last     CheckResult
+	lastRun  time.Time
+	hasCache bool
+}
+
+// checkRegistry holds the checks of a Telemetry instance in registration order.
+type checkRegistry struct {
+	cacheTTL time.Duration // Default for checks without CheckCacheTTL
+
+	mu     sync.Mutex
+	checks []*registeredCheck
+}
+
+// register adds the check, replacing an earlier one with the same name.
+func (r *checkRegistry) register(name string, checker Checker, opts ...CheckOption) {
+	check := &registeredCheck{
+		name:    name,
+		checker: checker,
+		checkConfig: checkConfig{
+			timeout:  defaultCheckTimeout,
+			cacheTTL: r.cacheTTL,
+			critical: true,
+			probes:   []Probe{ProbeReadiness, ProbeStartup},
+		},
+	}
+	for _, opt := range opts {
+		opt(&check.checkConfig)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.checks {
+		if existing.name == name {
+			r.checks[i] = check
+			return
+		}
+	}
+	r.checks = append(r.checks, check)
+}
+
+func (r *checkRegistry) list() []*registeredCheck {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*registeredCheck(nil), r.checks...)
+}
+
+// forProbe returns the checks that participate in probe.
+func (r *checkRegistry) forProbe(probe Probe) []*registeredCheck {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var checks []*registeredCheck
+	for _, check := range r.checks {
+		if slices.Contains(check.probes, probe) {
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// HealthStatus is the outcome of a single check or of the whole report.
+type HealthStatus string
+
+const (
+	// HealthStatusOK means every check passed.
+	HealthStatusOK HealthStatus = "ok"
+	// HealthStatusDegraded means only non-critical checks failed.
+	HealthStatusDegraded HealthStatus = "degraded"
+	// HealthStatusFail means at least one critical check failed.
+	HealthStatusFail HealthStatus = "fail"
+)
+
+// CheckResult is the outcome of one registered check.
+type CheckResult struct {
+	Name      string         `json:"name"`
+	Status    HealthStatus   `json:"status"`
+	Critical  bool           `json:"critical"`
+	Tags      []string       `json:"tags,omitempty"`
+	LatencyMS float64        `json:"latency_ms"`
+	Cached    bool           `json:"cached,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// HealthReport lists the results of the checks that ran, in registration order.
+// Draining is set when a readiness report failed because the instance is shutting down.
+type HealthReport struct {
+	Status   HealthStatus  `json:"status"`
+	Draining bool          `json:"draining,omitempty"`
+	Checks   []CheckResult `json:"checks"`
+}
+
+// RegisterCheck adds a named check to the default instance. See Telemetry.RegisterCheck.
+func RegisterCheck(name string, checker Checker, opts ...CheckOption) {
+	Default().RegisterCheck(name, checker, opts...)
+}
+
+// RegisterCheck adds a named check that HealthzEndpointHandler runs on every request.
+// Checks are critical unless CheckNonCritical is given. Registering a name again replaces
+// the earlier check, including the built-in "mysql", "nats" and "nats.healthz_event" checks.
+//
+//	tel.RegisterCheck("payments-api", telemetry.CheckerFunc(func(ctx context.Context) error {
+//	    return paymentsClient.Ping(ctx)
+//	}), telemetry.CheckTimeout(2*time.Second), telemetry.CheckNonCritical(), telemetry.CheckTags("external"))
+func (t *Telemetry) RegisterCheck(name string, checker Checker, opts ...CheckOption) {
+	t.checks.register(name, checker, opts...)
+}
+
+// CheckHealth runs all registered checks concurrently and returns their report.
+func (t *Telemetry) CheckHealth(ctx context.Context) HealthReport {
+	return runChecks(ctx, t.checks.list())
+}
+
+// runChecks runs checks concurrently and combines their results into a report.
+func runChecks(ctx context.Context, checks []*registeredCheck) HealthReport {
+	report := HealthReport{Status: HealthStatusOK, Checks: make([]CheckResult, len(checks))}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = check.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		switch {
+		case result.Status == HealthStatusOK:
+		case result.Critical:
+			report.Status = HealthStatusFail
+		case report.Status == HealthStatusOK:
+			report.Status = HealthStatusDegraded
+		}
+	}
+	return report
+}
+
+// run returns the cached result of the check or executes it within its timeout.
+func (c *registeredCheck) run(ctx context.Context) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hasCache && time.Since(c.lastRun) < c.cacheTTL {
+		result := c.last
+		result.Cached = true
+		return result
+	}
+
+	result := c.execute(ctx)
+	if c.cacheTTL > 0 {
+		c.last, c.lastRun, c.hasCache = result, time.Now(), true
+	}
+	return result
+}
+
+// execute runs the checker within the timeout of the check.
+func (c *registeredCheck) execute(ctx context.Context) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	type outcome struct {
+		details map[string]any
+		err     error
+	}
+	start := time.Now()
+	done := make(chan outcome, 1)
+	go func() {
+		if detailed, ok := c.checker.(DetailsChecker); ok {
+			details, err := detailed.CheckDetails(ctx)
+			done <- outcome{details: details, err: err}
+			return
+		}
+		done <- outcome{err: c.checker.Check(ctx)}
+	}()
+
+	var out outcome
+	select {
+	case out = <-done:
+	case <-ctx.Done():
+		out.err = fmt.Errorf("check timed out after %s: %w", c.timeout, ctx.Err())
+	}
+
+	result := CheckResult{
+		Name:      c.name,
+		Status:    HealthStatusOK,
+		Critical:  c.critical,
+		Tags:      c.tags,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		Details:   out.details,
+	}
+	if out.err != nil {
+		result.Status = HealthStatusFail
+		result.Error = out.err.Error()
+	}
+	return result
+}
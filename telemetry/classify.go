@@ -0,0 +1,136 @@
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+// ErrorAction defines what CaptureError does with errors of a class.
+type ErrorAction int
+
+const (
+	// ErrorActionCapture sends the error to Sentry and marks the span as failed. Errors
+	// that match no class get this action.
+	ErrorActionCapture ErrorAction = iota
+	// ErrorActionWarn sends the error to Sentry at warning level and records it on the
+	// span without failing it.
+	ErrorActionWarn
+	// ErrorActionLog only logs the error at info level.
+	ErrorActionLog
+	// ErrorActionIgnore drops the error entirely.
+	ErrorActionIgnore
+)
+
+func (a ErrorAction) String() string {
+	switch a {
+	case ErrorActionWarn:
+		return "warn"
+	case ErrorActionLog:
+		return "log"
+	case ErrorActionIgnore:
+		return "ignore"
+	default:
+		return "capture"
+	}
+}
+
+// ErrorMatcher reports whether err belongs to a class.
+type ErrorMatcher func(err error) bool
+
+// ErrorIs matches errors for which errors.Is(err, target) holds.
+func ErrorIs(target error) ErrorMatcher {
+	return func(err error) bool { return errors.Is(err, target) }
+}
+
+// ErrorAs matches errors for which errors.As finds an error of type T in the chain.
+func ErrorAs[T error]() ErrorMatcher {
+	return func(err error) bool {
+		var target T
+		return errors.As(err, &target)
+	}
+}
+
+type errorClass struct {
+	name   string
+	match  ErrorMatcher
+	action ErrorAction
+}
+
+// errorClassRegistry holds the error classes of a Telemetry instance. Classes registered
+// later are matched first.
+type errorClassRegistry struct {
+	mu      sync.RWMutex
+	classes []errorClass
+}
+
+// newErrorClassRegistry returns a registry with the built-in classes for errors that are
+// part of normal operation.
+func newErrorClassRegistry() *errorClassRegistry {
+	r := &errorClassRegistry{}
+	r.register("nats.timeout", ErrorIs(nats.ErrTimeout), ErrorActionWarn)
+	r.register("sql.no_rows", ErrorIs(sql.ErrNoRows), ErrorActionLog)
+	r.register("context.canceled", ErrorIs(context.Canceled), ErrorActionLog)
+	return r
+}
+
+func (r *errorClassRegistry) register(name string, match ErrorMatcher, action ErrorAction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	class := errorClass{name: name, match: match, action: action}
+	for i, c := range r.classes {
+		if c.name == name {
+			r.classes[i] = class
+			return
+		}
+	}
+	r.classes = append([]errorClass{class}, r.classes...)
+}
+
+// classify returns the first class matching err, or ErrorActionCapture and "" when none does.
+func (r *errorClassRegistry) classify(err error) (string, ErrorAction) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, c := range r.classes {
+		if c.match(err) {
+			return c.name, c.action
+		}
+	}
+	return "", ErrorActionCapture
+}
+
+// RegisterErrorClass registers an error class on the default instance. CaptureError, and
+// the HTTP and NATS helpers through it, apply the action of the first class that matches
+// an error. Classes registered later are matched first, and registering a name again
+// replaces the class. The built-in classes are:
+//
+//	context.canceled  ErrorIs(context.Canceled)  ErrorActionLog
+//	sql.no_rows       ErrorIs(sql.ErrNoRows)     ErrorActionLog
+//	nats.timeout      ErrorIs(nats.ErrTimeout)   ErrorActionWarn
+//
+// Example usage:
+//
+//	telemetry.RegisterErrorClass("not-found", telemetry.ErrorIs(ErrOrderNotFound), telemetry.ErrorActionIgnore)
+//	telemetry.RegisterErrorClass("validation", telemetry.ErrorAs[*ValidationError](), telemetry.ErrorActionLog)
+func RegisterErrorClass(name string, match ErrorMatcher, action ErrorAction) {
+	Default().RegisterErrorClass(name, match, action)
+}
+
+// RegisterErrorClass registers an error class on t. See RegisterErrorClass.
+func (t *Telemetry) RegisterErrorClass(name string, match ErrorMatcher, action ErrorAction) {
+	t.errorClasses.register(name, match, action)
+}
+
+// ClassifyError returns the name and action of the class of err on the default instance.
+// Errors that match no class are reported as "" and ErrorActionCapture.
+func ClassifyError(err error) (string, ErrorAction) {
+	return Default().ClassifyError(err)
+}
+
+// ClassifyError classifies err with the classes registered on t. See ClassifyError.
+func (t *Telemetry) ClassifyError(err error) (string, ErrorAction) {
+	return t.errorClasses.classify(err)
+}
@@ -0,0 +1,117 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestClassifyError_BuiltinClasses(t *testing.T) {
+	useDefault(t, Config{})
+
+	tests := []struct {
+		err    error
+		class  string
+		action ErrorAction
+	}{
+		{context.Canceled, "context.canceled", ErrorActionLog},
+		{fmt.Errorf("query order: %w", sql.ErrNoRows), "sql.no_rows", ErrorActionLog},
+		{fmt.Errorf("request reply: %w", nats.ErrTimeout), "nats.timeout", ErrorActionWarn},
+		{context.DeadlineExceeded, "", ErrorActionCapture},
+		{errors.New("boom"), "", ErrorActionCapture},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			class, action := ClassifyError(tt.err)
+			assert.Equal(t, tt.class, class)
+			assert.Equal(t, tt.action, action)
+		})
+	}
+}
+
+func TestRegisterErrorClass(t *testing.T) {
+	useDefault(t, Config{})
+	errNotFound := errors.New("order not found")
+
+	RegisterErrorClass("not-found", ErrorIs(errNotFound), ErrorActionIgnore)
+	RegisterErrorClass("url", ErrorAs[*url.Error](), ErrorActionWarn)
+	// Replaces the built-in class
+	RegisterErrorClass("context.canceled", ErrorIs(context.Canceled), ErrorActionIgnore)
+
+	class, action := ClassifyError(fmt.Errorf("load: %w", errNotFound))
+	assert.Equal(t, "not-found", class)
+	assert.Equal(t, ErrorActionIgnore, action)
+
+	// Classes registered later are matched first
+	class, action = ClassifyError(&url.Error{Op: "Get", URL: "/", Err: context.Canceled})
+	assert.Equal(t, "url", class)
+	assert.Equal(t, ErrorActionWarn, action)
+
+	_, action = ClassifyError(context.Canceled)
+	assert.Equal(t, ErrorActionIgnore, action)
+}
+
+func TestCaptureError_Classes(t *testing.T) {
+	events := recordSentryEvents(t)
+	var logs bytes.Buffer
+	tel := useDefault(t, Config{SentryEnabled: true, TraceEnabled: true})
+	tel.logger = slog.New(slog.NewTextHandler(&logs, nil))
+	RegisterErrorClass("ignored", ErrorIs(sql.ErrTxDone), ErrorActionIgnore)
+
+	ctx, spans, span := recordSpans(t)
+	CaptureError(ctx, context.Canceled, "client went away")
+	CaptureError(ctx, sql.ErrTxDone, "already committed")
+	span.End()
+
+	assert.Empty(t, events.all())
+	require.Len(t, spans.Ended(), 1)
+	assert.Equal(t, codes.Unset, spans.Ended()[0].Status().Code)
+	assert.Empty(t, spans.Ended()[0].Events())
+	assert.Contains(t, logs.String(), "level=INFO msg=\"Error captured\"")
+	assert.Contains(t, logs.String(), "error_class=context.canceled")
+	assert.NotContains(t, logs.String(), "already committed")
+
+	ctx, spans, span = recordSpans(t)
+	CaptureError(ctx, nats.ErrTimeout, "request timed out")
+	span.End()
+
+	require.Len(t, events.all(), 1)
+	assert.Equal(t, sentry.LevelWarning, events.all()[0].Level)
+	assert.Equal(t, "nats.timeout", events.all()[0].Tags["error.class"])
+	assert.Equal(t, codes.Unset, spans.Ended()[0].Status().Code)
+}
+
+func TestRecordError(t *testing.T) {
+	useDefault(t, Config{})
+
+	tests := []struct {
+		err    error
+		status codes.Code
+		events int
+	}{
+		{errors.New("connection refused"), codes.Error, 1},
+		{nats.ErrTimeout, codes.Unset, 1},
+		{context.Canceled, codes.Unset, 0},
+		{nil, codes.Unset, 0},
+	}
+	for _, tt := range tests {
+		ctx, spans, span := recordSpans(t)
+		RecordError(ctx, tt.err)
+		span.End()
+
+		require.Len(t, spans.Ended(), 1)
+		assert.Equal(t, tt.status, spans.Ended()[0].Status().Code, "%v", tt.err)
+		assert.Len(t, spans.Ended()[0].Events(), tt.events, "%v", tt.err)
+	}
+}
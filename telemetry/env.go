@@ -0,0 +1,168 @@
+package telemetry
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// FromEnv fills in settings from the standard environment variables:
+//
+//	OTEL_SERVICE_NAME            service name when Init gets an empty one
+//	OTEL_EXPORTER_OTLP_ENDPOINT  enables tracing with this exporter URL
+//	OTEL_TRACES_SAMPLER          always_on, always_off, traceidratio or their parentbased_ variants
+//	OTEL_TRACES_SAMPLER_ARG      ratio for the traceidratio samplers
+//	OTEL_RESOURCE_ATTRIBUTES     key1=value1,key2=value2 added to the resource
+//	SENTRY_DSN                   enables Sentry with this DSN
+//	SENTRY_RELEASE               Sentry release and service version
+//	LOG_LEVEL                    slog level (debug, info, warn, error)
+//	NATS_URL                     enables NATS with this URL
+//	MYSQL_DSN                    enables MySQL health checks with this DSN
+//
+// Explicit options always win over environment values, regardless of their order.
+// The merged configuration is available from Telemetry.Config.
+func FromEnv() Option {
+	return func(cfg *Config) { cfg.FromEnv = true }
+}
+
+// newConfig applies opts and, with FromEnv, fills the remaining gaps from the environment.
+func newConfig(serviceName string, environment string, opts ...Option) (*Config, error) {
+	cfg := &Config{
+		ServiceName: serviceName,
+		Environment: environment,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.FromEnv {
+		if err := applyEnv(cfg, os.Getenv); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// applyEnv sets every field of cfg that no explicit option has set from getenv.
+func applyEnv(cfg *Config, getenv func(string) string) error {
+	if v := getenv("OTEL_SERVICE_NAME"); v != "" && cfg.ServiceName == "" {
+		cfg.ServiceName = v
+	}
+
+	if v := getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); v != "" {
+		cfg.TraceEnabled = true
+		if cfg.TraceConfig.ExporterURL == "" {
+			cfg.TraceConfig.ExporterURL = v
+		}
+	}
+
+	if v := getenv("OTEL_TRACES_SAMPLER"); v != "" && cfg.TraceConfig.Sampler == nil && !cfg.TraceConfig.ParentBased {
+		sampler, parentBased, err := samplerFromEnv(v, getenv("OTEL_TRACES_SAMPLER_ARG"))
+		if err != nil {
+			return err
+		}
+		cfg.TraceConfig.Sampler = sampler
+		cfg.TraceConfig.ParentBased = parentBased
+	}
+
+	if v := getenv("OTEL_RESOURCE_ATTRIBUTES"); v != "" {
+		attrs, err := parseResourceAttributes(v)
+		if err != nil {
+			return err
+		}
+		if cfg.ResourceAttributes == nil {
+			cfg.ResourceAttributes = map[string]string{}
+		}
+		for k, v := range attrs {
+			if _, ok := cfg.ResourceAttributes[k]; !ok {
+				cfg.ResourceAttributes[k] = v
+			}
+		}
+	}
+
+	if v := getenv("SENTRY_DSN"); v != "" {
+		cfg.SentryEnabled = true
+		if cfg.SentryConfig.DSN == "" {
+			cfg.SentryConfig.DSN = v
+		}
+	}
+	if v := getenv("SENTRY_RELEASE"); v != "" && cfg.SentryConfig.Release == "" {
+		cfg.SentryConfig.Release = v
+	}
+
+	if v := getenv("LOG_LEVEL"); v != "" && !cfg.SlogConfig.logLevelSet {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(v)); err != nil {
+			return fmt.Errorf("invalid LOG_LEVEL: %w", err)
+		}
+		cfg.SlogEnabled = true
+		cfg.SlogConfig.logLevel = level
+		cfg.SlogConfig.logLevelSet = true
+	}
+
+	if v := getenv("NATS_URL"); v != "" {
+		cfg.NatsEnabled = true
+		if cfg.NatsConfig.URL == "" {
+			cfg.NatsConfig.URL = v
+		}
+	}
+
+	if v := getenv("MYSQL_DSN"); v != "" {
+		cfg.MysqlEnabled = true
+		if cfg.MysqlConfig.DSN == "" {
+			cfg.MysqlConfig.DSN = v
+		}
+	}
+
+	return nil
+}
+
+// samplerFromEnv maps OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG to a sampler and
+// whether it is parent-based.
+func samplerFromEnv(name string, arg string) (sdktrace.Sampler, bool, error) {
+	ratio := 1.0
+	if arg != "" {
+		var err error
+		if ratio, err = strconv.ParseFloat(arg, 64); err != nil {
+			return nil, false, fmt.Errorf("invalid OTEL_TRACES_SAMPLER_ARG: %w", err)
+		}
+	}
+
+	parentBased := strings.HasPrefix(name, "parentbased_")
+	switch strings.TrimPrefix(name, "parentbased_") {
+	case "always_on":
+		return sdktrace.AlwaysSample(), parentBased, nil
+	case "always_off":
+		return sdktrace.NeverSample(), parentBased, nil
+	case "traceidratio":
+		return sdktrace.TraceIDRatioBased(ratio), parentBased, nil
+	default:
+		return nil, false, fmt.Errorf("unsupported OTEL_TRACES_SAMPLER %q", name)
+	}
+}
+
+// parseResourceAttributes parses the key1=value1,key2=value2 format of OTEL_RESOURCE_ATTRIBUTES.
+// Values are percent-decoded.
+func parseResourceAttributes(s string) (map[string]string, error) {
+	attrs := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid OTEL_RESOURCE_ATTRIBUTES entry %q", pair)
+		}
+		decoded, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTEL_RESOURCE_ATTRIBUTES value for %q: %w", key, err)
+		}
+		attrs[key] = decoded
+	}
+	return attrs, nil
+}
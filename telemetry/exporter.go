@@ -0,0 +1,150 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+)
+
+// Protocol selects the transport of the OTLP exporter.
+type Protocol string
+
+const (
+	// ProtocolGRPC exports over OTLP/gRPC (default).
+	ProtocolGRPC Protocol = "grpc"
+	// ProtocolHTTPProtobuf exports over OTLP/HTTP with protobuf payloads.
+	ProtocolHTTPProtobuf Protocol = "http/protobuf"
+)
+
+// newTraceExporter builds the OTLP span exporter described by cfg.
+// Without TLS options, a full URL uses TLS for https and plaintext otherwise, and a
+// host:port is insecure, as it was before TLS was configurable.
+func newTraceExporter(ctx context.Context, cfg traceConfig) (sdktrace.SpanExporter, error) {
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Compression != "" && cfg.Compression != "gzip" && cfg.Compression != "none" {
+		return nil, fmt.Errorf("unsupported OTLP compression %q", cfg.Compression)
+	}
+	withURL := isEndpointURL(cfg.ExporterURL)
+
+	switch cfg.Protocol {
+	case "", ProtocolGRPC:
+		opts := []otlptracegrpc.Option{}
+		if withURL {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.ExporterURL))
+		} else {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.ExporterURL))
+		}
+		if tlsConfig != nil {
+			opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+		} else if !withURL {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
+		}
+		if cfg.Compression == "gzip" {
+			opts = append(opts, otlptracegrpc.WithCompressor(cfg.Compression))
+		}
+		if cfg.Timeout > 0 {
+			opts = append(opts, otlptracegrpc.WithTimeout(cfg.Timeout))
+		}
+		return otlptracegrpc.New(ctx, opts...)
+
+	case ProtocolHTTPProtobuf:
+		opts := []otlptracehttp.Option{}
+		if withURL {
+			opts = append(opts, otlptracehttp.WithEndpointURL(signalURL(cfg.ExporterURL, "/v1/traces")))
+		} else {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.ExporterURL))
+		}
+		if tlsConfig != nil {
+			opts = append(opts, otlptracehttp.WithTLSClientConfig(tlsConfig))
+		} else if !withURL {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		if cfg.Compression == "gzip" {
+			opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		}
+		if cfg.Timeout > 0 {
+			opts = append(opts, otlptracehttp.WithTimeout(cfg.Timeout))
+		}
+		return otlptracehttp.New(ctx, opts...)
+
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q", cfg.Protocol)
+	}
+}
+
+// sharedTransport returns the trace exporter settings for the metrics or log exporter of
+// signal, which falls back to the trace endpoint when it has no URL of its own. Those
+// exporters only speak OTLP/gRPC, so an OTLP/HTTP trace endpoint can't be shared.
+func (cfg traceConfig) sharedTransport(signal string) (traceConfig, error) {
+	if cfg.ExporterURL == "" {
+		return traceConfig{}, fmt.Errorf("OpenTelemetry %s exporter URL is required but not set", signal)
+	}
+	if cfg.Protocol != "" && cfg.Protocol != ProtocolGRPC {
+		return traceConfig{}, fmt.Errorf("OpenTelemetry %s exporter URL is required when traces are exported over %s", signal, cfg.Protocol)
+	}
+	return cfg, nil
+}
+
+// isEndpointURL reports whether an exporter endpoint is a full URL (scheme://host:port/path)
+// rather than a host:port. The scheme of a URL decides between TLS and plaintext.
+func isEndpointURL(endpoint string) bool {
+	return strings.Contains(endpoint, "://")
+}
+
+// signalURL sets path on an OTLP/HTTP endpoint URL without one, as the OpenTelemetry spec
+// asks for OTEL_EXPORTER_OTLP_ENDPOINT. The exporter only does this for an empty path, not
+// for a bare "/". A URL with a path is used as is.
+func signalURL(endpoint string, path string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || strings.Trim(u.Path, "/") != "" {
+		return endpoint
+	}
+	u.Path = path
+	return u.String()
+}
+
+// tlsConfig returns the TLS configuration for the exporter, or nil for an insecure connection.
+func (cfg traceConfig) tlsConfig() (*tls.Config, error) {
+	if !cfg.TLS.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.TLS.CAFile != "" {
+		caPEM, err := os.ReadFile(cfg.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", cfg.TLS.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.TLS.CertFile != "" || cfg.TLS.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,402 @@
+package telemetry
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// testPKI holds a CA with a server certificate for 127.0.0.1 and a client certificate,
+// written as PEM files so they can be passed to TraceTLS and TraceClientCert.
+type testPKI struct {
+	caFile, clientCertFile, clientKeyFile string
+	caPool                                *x509.CertPool
+	serverCert                            tls.Certificate
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "localhost"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+
+	pki := testPKI{
+		caFile:         filepath.Join(dir, "ca.pem"),
+		clientCertFile: filepath.Join(dir, "client.pem"),
+		clientKeyFile:  filepath.Join(dir, "client-key.pem"),
+		caPool:         x509.NewCertPool(),
+	}
+	pki.caPool.AddCert(caCert)
+	require.NoError(t, os.WriteFile(pki.caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0o600))
+
+	clientCert, clientKey := issue(2, x509.ExtKeyUsageClientAuth)
+	require.NoError(t, os.WriteFile(pki.clientCertFile, clientCert, 0o600))
+	require.NoError(t, os.WriteFile(pki.clientKeyFile, clientKey, 0o600))
+
+	serverCert, serverKey := issue(3, x509.ExtKeyUsageServerAuth)
+	pki.serverCert, err = tls.X509KeyPair(serverCert, serverKey)
+	require.NoError(t, err)
+	return pki
+}
+
+// serverTLS requires clients to present a certificate issued by the test CA.
+func (pki testPKI) serverTLS() *tls.Config {
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{pki.serverCert},
+		ClientCAs:    pki.caPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+}
+
+// exportedRequest is what a fake collector received.
+type exportedRequest struct {
+	header http.Header
+	path   string
+	spans  []string
+}
+
+func spanNames(req *coltracepb.ExportTraceServiceRequest) []string {
+	var names []string
+	for _, rs := range req.GetResourceSpans() {
+		for _, ss := range rs.GetScopeSpans() {
+			for _, span := range ss.GetSpans() {
+				names = append(names, span.GetName())
+			}
+		}
+	}
+	return names
+}
+
+// newFakeHTTPCollector accepts OTLP/HTTP protobuf exports on /v1/traces.
+func newFakeHTTPCollector(t *testing.T, tlsConfig *tls.Config) (*httptest.Server, chan exportedRequest) {
+	t.Helper()
+	received := make(chan exportedRequest, 10)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = gz
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req := &coltracepb.ExportTraceServiceRequest{}
+		if err := proto.Unmarshal(data, req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received <- exportedRequest{header: r.Header.Clone(), path: r.URL.Path, spans: spanNames(req)}
+
+		resp, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = w.Write(resp)
+	}))
+	if tlsConfig != nil {
+		server.TLS = tlsConfig
+		server.StartTLS()
+	} else {
+		server.Start()
+	}
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+type fakeGRPCCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+	received chan exportedRequest
+}
+
+func (c *fakeGRPCCollector) Export(
+	ctx context.Context,
+	req *coltracepb.ExportTraceServiceRequest,
+) (*coltracepb.ExportTraceServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := http.Header{}
+	for k, v := range md {
+		header[http.CanonicalHeaderKey(k)] = v
+	}
+	c.received <- exportedRequest{header: header, spans: spanNames(req)}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+// newFakeGRPCCollector accepts OTLP/gRPC exports and returns its host:port.
+func newFakeGRPCCollector(t *testing.T, tlsConfig *tls.Config) (string, chan exportedRequest) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	server := grpc.NewServer(opts...)
+	collector := &fakeGRPCCollector{received: make(chan exportedRequest, 10)}
+	coltracepb.RegisterTraceServiceServer(server, collector)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+	return lis.Addr().String(), collector.received
+}
+
+// exportSpan sends one span through an exporter built from opts.
+func exportSpan(t *testing.T, name string, opts ...TraceOption) {
+	t.Helper()
+	cfg := traceConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	exporter, err := newTraceExporter(context.Background(), cfg)
+	require.NoError(t, err)
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	_, span := tp.Tracer("exporter-test").Start(context.Background(), name)
+	span.End()
+	require.NoError(t, tp.Shutdown(context.Background()))
+}
+
+func waitForExport(t *testing.T, received chan exportedRequest) exportedRequest {
+	t.Helper()
+	select {
+	case req := <-received:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("collector received no export")
+		return exportedRequest{}
+	}
+}
+
+func TestNewTraceExporter_HTTPInsecure(t *testing.T) {
+	server, received := newFakeHTTPCollector(t, nil)
+
+	exportSpan(t, "http-insecure",
+		TraceExporterURL(server.URL+"/v1/traces"),
+		TraceProtocol(ProtocolHTTPProtobuf),
+	)
+
+	req := waitForExport(t, received)
+	assert.Equal(t, []string{"http-insecure"}, req.spans)
+	assert.Equal(t, "/v1/traces", req.path)
+}
+
+func TestNewTraceExporter_HTTPURLWithoutPath(t *testing.T) {
+	server, received := newFakeHTTPCollector(t, nil)
+
+	// A generic OTEL_EXPORTER_OTLP_ENDPOINT carries no signal path, sometimes a bare "/"
+	exportSpan(t, "http-no-path",
+		TraceExporterURL(server.URL+"/"),
+		TraceProtocol(ProtocolHTTPProtobuf),
+	)
+
+	req := waitForExport(t, received)
+	assert.Equal(t, []string{"http-no-path"}, req.spans)
+	assert.Equal(t, "/v1/traces", req.path)
+}
+
+func TestNewTraceExporter_HTTPWithTLSHeadersAndCompression(t *testing.T) {
+	pki := newTestPKI(t)
+	server, received := newFakeHTTPCollector(t, pki.serverTLS())
+
+	exportSpan(t, "http-tls",
+		TraceExporterURL(server.Listener.Addr().String()),
+		TraceProtocol(ProtocolHTTPProtobuf),
+		TraceTLS(pki.caFile),
+		TraceClientCert(pki.clientCertFile, pki.clientKeyFile),
+		TraceHeaders(map[string]string{"x-api-key": "secret"}),
+		TraceCompression("gzip"),
+		TraceTimeout(5*time.Second),
+	)
+
+	req := waitForExport(t, received)
+	assert.Equal(t, []string{"http-tls"}, req.spans)
+	assert.Equal(t, "secret", req.header.Get("X-Api-Key"))
+	assert.Equal(t, "gzip", req.header.Get("Content-Encoding"))
+}
+
+func TestNewTraceExporter_GRPCInsecure(t *testing.T) {
+	addr, received := newFakeGRPCCollector(t, nil)
+
+	exportSpan(t, "grpc-insecure", TraceExporterURL(addr))
+
+	req := waitForExport(t, received)
+	assert.Equal(t, []string{"grpc-insecure"}, req.spans)
+}
+
+func TestNewTraceExporter_GRPCWithTLSHeadersAndCompression(t *testing.T) {
+	pki := newTestPKI(t)
+	addr, received := newFakeGRPCCollector(t, pki.serverTLS())
+
+	exportSpan(t, "grpc-tls",
+		TraceExporterURL(addr),
+		TraceProtocol(ProtocolGRPC),
+		TraceTLS(pki.caFile),
+		TraceClientCert(pki.clientCertFile, pki.clientKeyFile),
+		TraceHeaders(map[string]string{"x-api-key": "secret"}),
+		TraceCompression("gzip"),
+		TraceTimeout(5*time.Second),
+	)
+
+	req := waitForExport(t, received)
+	assert.Equal(t, []string{"grpc-tls"}, req.spans)
+	assert.Equal(t, "secret", req.header.Get("X-Api-Key"))
+}
+
+// newHandshakeListener accepts one connection and reports whether the client opened it
+// with a TLS handshake record rather than plaintext.
+func newHandshakeListener(t *testing.T) (string, chan bool) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lis.Close() })
+
+	isTLS := make(chan bool, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		first := make([]byte, 1)
+		if _, err := io.ReadFull(conn, first); err == nil {
+			isTLS <- first[0] == 0x16 // TLS handshake record
+		}
+	}()
+	return lis.Addr().String(), isTLS
+}
+
+func TestNewTraceExporter_HTTPSURLWithoutTLSOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol Protocol
+		path     string
+	}{
+		{name: "http/protobuf", protocol: ProtocolHTTPProtobuf, path: "/v1/traces"},
+		{name: "grpc", protocol: ProtocolGRPC},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, isTLS := newHandshakeListener(t)
+
+			// The listener is no real collector, so the export itself fails
+			exportSpan(t, "https-url",
+				TraceExporterURL("https://"+addr+tt.path),
+				TraceProtocol(tt.protocol),
+				TraceTimeout(500*time.Millisecond),
+			)
+
+			select {
+			case got := <-isTLS:
+				assert.True(t, got, "the exporter connected without TLS")
+			case <-time.After(5 * time.Second):
+				t.Fatal("exporter never connected")
+			}
+		})
+	}
+}
+
+func TestNewTraceExporter_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []TraceOption
+		wantErr string
+	}{
+		{
+			name:    "unsupported protocol",
+			opts:    []TraceOption{TraceProtocol("thrift")},
+			wantErr: "unsupported OTLP protocol",
+		},
+		{
+			name:    "unsupported compression",
+			opts:    []TraceOption{TraceCompression("zstd")},
+			wantErr: "unsupported OTLP compression",
+		},
+		{
+			name:    "missing CA file",
+			opts:    []TraceOption{TraceTLS("/does/not/exist.pem")},
+			wantErr: "failed to read CA file",
+		},
+		{
+			name:    "missing client certificate",
+			opts:    []TraceOption{TraceClientCert("/does/not/exist.pem", "/does/not/exist-key.pem")},
+			wantErr: "failed to load client certificate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := traceConfig{ExporterURL: "127.0.0.1:4317"}
+			for _, opt := range tt.opts {
+				opt(&cfg)
+			}
+
+			_, err := newTraceExporter(context.Background(), cfg)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -1,90 +1,99 @@
 package telemetry
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/TMSLabs/go-tooling/mysqlhelper"
+	"github.com/jmoiron/sqlx"
 	"github.com/nats-io/nats.go"
 )
 
-var (
-	// LastHealthCheckEvent stores the timestamp of the last health check event.
-	LastHealthCheckEvent = ""
-)
-
-// HealthzEventChecker subscribes to the health check and publishes health check events periodically.
-func HealthzEventChecker(nc *nats.Conn, serviceName string) {
-	_, err := nc.Subscribe(serviceName+".healthz", func(_ *nats.Msg) {
-		// fmt.Printf("Received health check event\n")
-		LastHealthCheckEvent = time.Now().Format(time.RFC3339)
-	})
-	if err != nil {
-		slog.Error("Error subscribing to health check event", "error", err)
-		return
+// registerBuiltinChecks registers the MySQL and NATS checks enabled in t's configuration.
+func (t *Telemetry) registerBuiltinChecks() {
+	if t.cfg.MysqlEnabled {
+		var checker Checker
+		if db := t.cfg.MysqlConfig.DB; db != nil {
+			checker = MySQLChecker(db)
+		} else {
+			dsn := t.cfg.MysqlConfig.DSN
+			checker = CheckerFunc(func(_ context.Context) error {
+				if err := mysqlhelper.CheckConnection(dsn); err != nil {
+					return fmt.Errorf("MySQL connection failed: %w", err)
+				}
+				return nil
+			})
+		}
+		t.RegisterCheck("mysql", checker, CheckTags("database"))
 	}
 
-	for {
-		data := []byte("Health check event")
-		err := nc.Publish(serviceName+".healthz", data)
-		if err != nil {
-			slog.Error("Error publishing health check event", "error", err)
-			return
-		}
-		time.Sleep(60 * time.Second)
+	if t.cfg.NatsEnabled {
+		t.registerNATSCheck(t.cfg.NatsConfig.Conn)
+		t.RegisterCheck("nats.healthz_event", heartbeatChecker{t: t}, CheckTags("messaging"))
 	}
 }
 
-// HealthzEndpointHandler handles the health check endpoint for the service.
-func HealthzEndpointHandler(w http.ResponseWriter, _ *http.Request) {
-
-	if TelemetryConfig.MysqlEnabled {
-		if err := mysqlhelper.CheckConnection(TelemetryConfig.MysqlConfig.DSN); err != nil {
-			slog.Error("MySQL connection check failed", "error", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			_, _ = fmt.Fprintln(w, "MySQL connection failed:", err)
-			return
-		}
+// registerNATSCheck registers the "nats" check. It inspects nc when set and dials the
+// configured URL otherwise.
+func (t *Telemetry) registerNATSCheck(nc *nats.Conn) {
+	var checker Checker
+	if nc != nil {
+		checker = NATSChecker(nc)
+	} else {
+		url := t.cfg.NatsConfig.URL
+		checker = CheckerFunc(func(_ context.Context) error {
+			if err := CheckConnection(url); err != nil {
+				return fmt.Errorf("NATS connection failed: %w", err)
+			}
+			return nil
+		})
 	}
+	t.RegisterCheck("nats", checker, CheckTags("messaging"))
+}
 
-	if TelemetryConfig.NatsEnabled {
-		if err := CheckConnection(TelemetryConfig.NatsConfig.URL); err != nil {
-			slog.Error("NATS connection check failed", "error", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			_, _ = fmt.Fprintln(w, "NATS connection failed:", err)
-			return
-		}
+// HealthzEndpointHandler handles the health check endpoint for the service.
+// It runs all checks registered on the default instance, regardless of their probes.
+// Kubernetes probes should use LivezEndpointHandler, ReadyzEndpointHandler and
+// StartupzEndpointHandler instead.
+func HealthzEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	Default().HealthzEndpointHandler(w, r)
+}
 
-		if LastHealthCheckEvent == "" {
-			slog.Warn("No health check event received yet")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			_, _ = fmt.Fprintln(w, "No health check event received yet")
-			return
-		}
+// HealthzEndpointHandler runs the checks registered on t concurrently and responds with a
+// JSON HealthReport. The status code is 503 when a critical check fails and 200 otherwise:
+//
+//	{"status":"fail","checks":[
+//	  {"name":"mysql","status":"fail","critical":true,"tags":["database"],"latency_ms":2.1,"error":"MySQL connection failed: ..."},
+//	  {"name":"nats","status":"ok","critical":true,"tags":["messaging"],"latency_ms":0.8}
+//	]}
+func (t *Telemetry) HealthzEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	t.writeHealthReport(w, t.CheckHealth(r.Context()))
+}
 
-		if LastHealthCheckEvent < time.Now().Add(-5*time.Minute).Format(time.RFC3339) {
-			slog.Warn(
-				"Last health check event is older than 5 minutes",
-				"last_event",
-				LastHealthCheckEvent,
+// writeHealthReport logs the failed checks of report and writes it as JSON, with 503 when
+// a critical check failed and 200 otherwise.
+func (t *Telemetry) writeHealthReport(w http.ResponseWriter, report HealthReport) {
+	logger := t.Logger()
+	for _, result := range report.Checks {
+		if result.Status != HealthStatusOK {
+			logger.Warn("Health check failed",
+				"check", result.Name,
+				"critical", result.Critical,
+				"error", result.Error,
 			)
-			w.WriteHeader(http.StatusServiceUnavailable)
-			_, _ = fmt.Fprintln(w, "Last health check event is older than 5 minutes")
-			return
 		}
-
-		slog.Debug(
-			"Health check event received",
-			"last_event",
-			LastHealthCheckEvent,
-		)
 	}
 
-	w.WriteHeader(http.StatusOK)
-	_, _ = fmt.Fprintln(w, "{\"status\": \"ok\", \"message\": \"Service is healthy\"}")
-
+	status := http.StatusOK
+	if report.Status == HealthStatusFail {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
 }
 
 // CheckConnection checks if the NATS server is reachable.
@@ -97,3 +106,66 @@ func CheckConnection(dsn string) error {
 
 	return nil
 }
+
+// MySQLChecker returns a check that pings the existing pool db and reports its
+// connection statistics.
+func MySQLChecker(db *sqlx.DB) Checker {
+	return mysqlChecker{db: db}
+}
+
+type mysqlChecker struct {
+	db *sqlx.DB
+}
+
+func (c mysqlChecker) Check(ctx context.Context) error {
+	_, err := c.CheckDetails(ctx)
+	return err
+}
+
+func (c mysqlChecker) CheckDetails(ctx context.Context) (map[string]any, error) {
+	if err := mysqlhelper.CheckDB(ctx, c.db); err != nil {
+		return nil, fmt.Errorf("MySQL connection failed: %w", err)
+	}
+	stats := c.db.Stats()
+	return map[string]any{
+		"open_connections": stats.OpenConnections,
+		"in_use":           stats.InUse,
+		"wait_count":       stats.WaitCount,
+	}, nil
+}
+
+// NATSChecker returns a check that inspects the existing connection nc instead of dialing.
+// It fails unless nc is connected and answers a round trip to the server, and reports the
+// round-trip time and the reconnect counter.
+func NATSChecker(nc *nats.Conn) Checker {
+	return natsChecker{nc: nc}
+}
+
+type natsChecker struct {
+	nc *nats.Conn
+}
+
+func (c natsChecker) Check(ctx context.Context) error {
+	_, err := c.CheckDetails(ctx)
+	return err
+}
+
+func (c natsChecker) CheckDetails(ctx context.Context) (map[string]any, error) {
+	if c.nc == nil {
+		return nil, fmt.Errorf("NATS connection failed: no connection")
+	}
+	details := map[string]any{
+		"status":     c.nc.Status().String(),
+		"reconnects": c.nc.Stats().Reconnects,
+	}
+	if status := c.nc.Status(); status != nats.CONNECTED {
+		return details, fmt.Errorf("NATS connection failed: connection is %s", status)
+	}
+
+	start := time.Now()
+	if err := c.nc.FlushWithContext(ctx); err != nil {
+		return details, fmt.Errorf("NATS connection failed: round trip: %w", err)
+	}
+	details["rtt_ms"] = float64(time.Since(start).Microseconds()) / 1000
+	return details, nil
+}
@@ -0,0 +1,179 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// defaultHeartbeatInterval is how often a health check event is published without
+	// NATSHeartbeatInterval.
+	defaultHeartbeatInterval = 60 * time.Second
+	// defaultHeartbeatStaleAfter is how old the last event may get without NATSHeartbeatStaleAfter.
+	defaultHeartbeatStaleAfter = 5 * time.Minute
+	// heartbeatRetries is how often a failed publish is retried before waiting for the next interval.
+	heartbeatRetries = 3
+)
+
+// healthState records when the last health check event of this instance came back over
+// NATS, how long the round trip took, and whether the instance is draining.
+type healthState struct {
+	id string // Tells this instance's events apart from those of other replicas
+
+	mu        sync.Mutex
+	lastEvent time.Time
+	lastRTT   time.Duration
+	draining  atomic.Bool
+}
+
+func newHealthState() *healthState {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return &healthState{id: hex.EncodeToString(id)}
+}
+
+func (h *healthState) recordRoundTrip(at time.Time, rtt time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastEvent = at
+	h.lastRTT = rtt
+}
+
+func (h *healthState) roundTrip() (time.Time, time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastEvent, h.lastRTT
+}
+
+// heartbeat encodes a health check event as "<instance id> <send time in unix nanoseconds>".
+func (h *healthState) heartbeat(sent time.Time) []byte {
+	return []byte(h.id + " " + strconv.FormatInt(sent.UnixNano(), 10))
+}
+
+// receive records an event published by this instance. Events of other replicas that
+// share the subject are ignored.
+func (h *healthState) receive(data []byte, received time.Time) {
+	id, sentNanos, ok := strings.Cut(string(data), " ")
+	if !ok || id != h.id {
+		return
+	}
+	sent, err := strconv.ParseInt(sentNanos, 10, 64)
+	if err != nil {
+		return
+	}
+	h.recordRoundTrip(received, received.Sub(time.Unix(0, sent)))
+}
+
+// HealthzEventChecker subscribes to the health check subject and publishes health check
+// events periodically until ctx is cancelled. Received events are recorded in the health
+// state of the default instance.
+func HealthzEventChecker(ctx context.Context, nc *nats.Conn, serviceName string) {
+	Default().runHealthzEvents(ctx, nc, serviceName)
+}
+
+// runHealthzEvents subscribes to the health check subject and publishes health check
+// events periodically until ctx is cancelled or the connection is closed.
+func (t *Telemetry) runHealthzEvents(ctx context.Context, nc *nats.Conn, serviceName string) {
+	subject := serviceName + ".healthz"
+	sub, err := nc.Subscribe(subject, func(msg *nats.Msg) {
+		t.health.receive(msg.Data, time.Now())
+	})
+	if err != nil {
+		t.Logger().Error("Error subscribing to health check event", "error", err)
+		return
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	t.publishHeartbeats(ctx, func(data []byte) error {
+		return nc.Publish(subject, data)
+	})
+}
+
+// publishHeartbeats publishes a health check event every heartbeat interval until ctx is
+// cancelled or publish reports a closed connection. Other errors are retried with backoff.
+func (t *Telemetry) publishHeartbeats(ctx context.Context, publish func(data []byte) error) {
+	interval := t.cfg.NatsConfig.HeartbeatInterval
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.publishHeartbeat(ctx, interval, publish); errors.Is(err, nats.ErrConnectionClosed) {
+			t.Logger().Error("Stopping health check events", "error", err)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishHeartbeat publishes one health check event, retrying transient errors with a
+// backoff that starts at a quarter of interval, capped at one second.
+func (t *Telemetry) publishHeartbeat(ctx context.Context, interval time.Duration, publish func(data []byte) error) error {
+	backoff := min(interval/4, time.Second)
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = publish(t.health.heartbeat(time.Now())); err == nil {
+			return nil
+		}
+		if errors.Is(err, nats.ErrConnectionClosed) || attempt == heartbeatRetries {
+			break
+		}
+		t.Logger().Warn("Retrying health check event", "error", err, "attempt", attempt+1)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	t.Logger().Error("Error publishing health check event", "error", err)
+	return err
+}
+
+// heartbeatChecker fails when no health check event of t came back over NATS within the
+// staleness threshold, and reports the round-trip time of the last one.
+type heartbeatChecker struct {
+	t *Telemetry
+}
+
+func (c heartbeatChecker) Check(ctx context.Context) error {
+	_, err := c.CheckDetails(ctx)
+	return err
+}
+
+func (c heartbeatChecker) CheckDetails(_ context.Context) (map[string]any, error) {
+	staleAfter := c.t.cfg.NatsConfig.HeartbeatStaleAfter
+	if staleAfter <= 0 {
+		staleAfter = defaultHeartbeatStaleAfter
+	}
+
+	lastEvent, rtt := c.t.health.roundTrip()
+	if lastEvent.IsZero() {
+		return nil, fmt.Errorf("no health check event received yet")
+	}
+	details := map[string]any{
+		"last_event": lastEvent.UTC().Format(time.RFC3339Nano),
+		"rtt_ms":     float64(rtt.Microseconds()) / 1000,
+	}
+	if age := time.Since(lastEvent); age > staleAfter {
+		return details, fmt.Errorf("last health check event is older than %s (age %s)",
+			staleAfter, age.Round(time.Second))
+	}
+	return details, nil
+}
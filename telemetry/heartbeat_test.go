@@ -0,0 +1,153 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePublisher records published heartbeats and fails the first failures calls with err.
+type fakePublisher struct {
+	mu        sync.Mutex
+	published [][]byte
+	failures  int
+	err       error
+}
+
+func (p *fakePublisher) publish(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures > 0 {
+		p.failures--
+		return p.err
+	}
+	p.published = append(p.published, data)
+	return nil
+}
+
+func (p *fakePublisher) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.published)
+}
+
+func TestHealthState_ReceiveOwnHeartbeat(t *testing.T) {
+	health := newHealthState()
+	sent := time.Now()
+
+	health.receive(health.heartbeat(sent), sent.Add(15*time.Millisecond))
+
+	lastEvent, rtt := health.roundTrip()
+	assert.Equal(t, sent.Add(15*time.Millisecond), lastEvent)
+	assert.Equal(t, 15*time.Millisecond, rtt)
+}
+
+func TestHealthState_IgnoresOtherReplicas(t *testing.T) {
+	health := newHealthState()
+	other := newHealthState()
+
+	health.receive(other.heartbeat(time.Now()), time.Now())
+	health.receive([]byte("Health check event"), time.Now())
+
+	last, _ := health.roundTrip()
+	assert.True(t, last.IsZero())
+}
+
+func TestPublishHeartbeats_Interval(t *testing.T) {
+	tel := newTelemetry(Config{NatsConfig: natsConfig{HeartbeatInterval: 10 * time.Millisecond}})
+	publisher := &fakePublisher{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+	tel.publishHeartbeats(ctx, publisher.publish)
+
+	// One event right away and then one per interval, until ctx is done
+	assert.GreaterOrEqual(t, publisher.count(), 4)
+	assert.LessOrEqual(t, publisher.count(), 7)
+}
+
+func TestPublishHeartbeats_RetriesTransientErrors(t *testing.T) {
+	// A 40ms interval retries after 10ms and 20ms
+	tel := newTelemetry(Config{NatsConfig: natsConfig{HeartbeatInterval: 40 * time.Millisecond}})
+	publisher := &fakePublisher{failures: 2, err: errors.New("nats: slow consumer")}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 70*time.Millisecond)
+	defer cancel()
+	tel.publishHeartbeats(ctx, publisher.publish)
+
+	assert.Zero(t, publisher.failures)
+	assert.GreaterOrEqual(t, publisher.count(), 1)
+}
+
+func TestPublishHeartbeats_KeepsRunningAfterRetriesFail(t *testing.T) {
+	tel := newTelemetry(Config{NatsConfig: natsConfig{HeartbeatInterval: 20 * time.Millisecond}})
+	publisher := &fakePublisher{failures: heartbeatRetries + 1, err: errors.New("nats: timeout")}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	tel.publishHeartbeats(ctx, publisher.publish)
+
+	assert.Positive(t, publisher.count())
+}
+
+func TestPublishHeartbeats_StopsOnClosedConnection(t *testing.T) {
+	tel := newTelemetry(Config{NatsConfig: natsConfig{HeartbeatInterval: 10 * time.Millisecond}})
+	publisher := &fakePublisher{failures: 100, err: nats.ErrConnectionClosed}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tel.publishHeartbeats(context.Background(), publisher.publish)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("heartbeat loop kept running on a closed connection")
+	}
+	assert.Equal(t, 99, publisher.failures) // No retries either
+}
+
+func TestPublishHeartbeats_StopsOnCancel(t *testing.T) {
+	tel := newTelemetry(Config{}) // Default 60s interval
+	publisher := &fakePublisher{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tel.publishHeartbeats(ctx, publisher.publish)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("heartbeat loop ignored the cancelled context")
+	}
+}
+
+func TestHeartbeatChecker_StaleAfter(t *testing.T) {
+	tel := newTelemetry(Config{NatsConfig: natsConfig{HeartbeatStaleAfter: 30 * time.Second}})
+	checker := heartbeatChecker{t: tel}
+
+	_, err := checker.CheckDetails(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no health check event received yet")
+
+	tel.health.recordRoundTrip(time.Now().Add(-10*time.Second), 3*time.Millisecond)
+	details, err := checker.CheckDetails(context.Background())
+	require.NoError(t, err)
+	assert.InDelta(t, 3.0, details["rtt_ms"], 0.001)
+
+	tel.health.recordRoundTrip(time.Now().Add(-time.Minute), 3*time.Millisecond)
+	_, err = checker.CheckDetails(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "older than 30s")
+}
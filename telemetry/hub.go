@@ -0,0 +1,30 @@
+package telemetry
+
+import (
+	"context"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ContextWithHub returns a copy of ctx carrying its own Sentry hub, cloned from the hub
+// already on ctx or from the global hub. Breadcrumbs added through AddBreadcrumb with the
+// returned context stay with this request or message, so concurrent work doesn't mix its
+// trail into each other's error reports. httphelper.HTTPHandler and the natshelper
+// subscribe wrappers call it for every request and message.
+func ContextWithHub(ctx context.Context) context.Context {
+	return sentry.SetHubOnContext(ctx, HubFromContext(ctx).Clone())
+}
+
+// HubFromContext returns the Sentry hub stored on ctx by ContextWithHub, or the global
+// hub when there is none.
+func HubFromContext(ctx context.Context) *sentry.Hub {
+	if hub := sentry.GetHubFromContext(ctx); hub != nil {
+		return hub
+	}
+	return sentry.CurrentHub()
+}
+
+// AddBreadcrumb records breadcrumb on the Sentry hub of ctx. See HubFromContext.
+func AddBreadcrumb(ctx context.Context, breadcrumb *sentry.Breadcrumb) {
+	HubFromContext(ctx).AddBreadcrumb(breadcrumb, nil)
+}
@@ -0,0 +1,49 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHubFromContext_FallsBackToCurrentHub(t *testing.T) {
+	assert.Same(t, sentry.CurrentHub(), HubFromContext(context.Background()))
+
+	ctx := ContextWithHub(context.Background())
+	assert.NotSame(t, sentry.CurrentHub(), HubFromContext(ctx))
+	assert.Same(t, HubFromContext(ctx), HubFromContext(ctx))
+}
+
+func TestContextWithHub_IsolatesBreadcrumbs(t *testing.T) {
+	events := recordSentryEvents(t)
+	useDefault(t, Config{SentryEnabled: true})
+
+	first := ContextWithHub(context.Background())
+	second := ContextWithHub(context.Background())
+	AddBreadcrumb(first, &sentry.Breadcrumb{Message: "first request"})
+	AddBreadcrumb(second, &sentry.Breadcrumb{Message: "second request"})
+
+	CaptureError(first, errors.New("boom"), "first failed")
+	CaptureError(second, errors.New("boom"), "second failed")
+
+	require.Len(t, events.all(), 2)
+	assert.Equal(t, []string{"first request", "first failed"}, breadcrumbMessages(events.all()[0]))
+	assert.Equal(t, []string{"second request", "second failed"}, breadcrumbMessages(events.all()[1]))
+
+	// Nothing reached the global hub
+	CaptureMessage(context.Background(), "global")
+	require.Len(t, events.all(), 3)
+	assert.Empty(t, breadcrumbMessages(events.all()[2]))
+}
+
+func breadcrumbMessages(event *sentry.Event) []string {
+	var messages []string
+	for _, b := range event.Breadcrumbs {
+		messages = append(messages, b.Message)
+	}
+	return messages
+}
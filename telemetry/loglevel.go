@@ -0,0 +1,148 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// levelState holds the active log level of a Telemetry instance and an optional pending revert.
+type levelState struct {
+	level slog.LevelVar
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	revertTo slog.Level
+	revertAt time.Time
+}
+
+// reset sets the level and cancels any pending revert.
+func (s *levelState) reset(level slog.Level) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopRevert()
+	s.level.Set(level)
+}
+
+// cancelRevert cancels any pending revert and keeps the current level.
+func (s *levelState) cancelRevert() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopRevert()
+}
+
+// set changes the level. With a positive ttl, the level reverts to the value it had
+// before the first unexpired change once ttl has elapsed, and reverted is called with it.
+func (s *levelState) set(level slog.Level, ttl time.Duration, reverted func(level slog.Level)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revertTo := s.level.Level()
+	if s.timer != nil {
+		revertTo = s.revertTo
+	}
+	s.stopRevert()
+	s.level.Set(level)
+
+	if ttl <= 0 {
+		return
+	}
+	s.revertTo = revertTo
+	s.revertAt = time.Now().Add(ttl)
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		s.mu.Lock()
+		if s.timer != timer {
+			s.mu.Unlock()
+			return // Superseded by a later change
+		}
+		s.level.Set(revertTo)
+		s.timer = nil
+		s.mu.Unlock()
+		reverted(revertTo)
+	})
+	s.timer = timer
+}
+
+// stopRevert cancels the pending revert. The caller must hold s.mu.
+func (s *levelState) stopRevert() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+// status returns the current level and, if a revert is pending, when it happens.
+func (s *levelState) status() logLevelResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := logLevelResponse{Level: s.level.Level().String()}
+	if s.timer != nil {
+		resp.RevertTo = s.revertTo.String()
+		resp.RevertAt = s.revertAt.UTC().Format(time.RFC3339)
+	}
+	return resp
+}
+
+type logLevelRequest struct {
+	Level string `json:"level"`
+	TTL   string `json:"ttl,omitempty"`
+}
+
+type logLevelResponse struct {
+	Level    string `json:"level"`
+	RevertTo string `json:"revert_to,omitempty"`
+	RevertAt string `json:"revert_at,omitempty"`
+}
+
+// LogLevelEndpointHandler reads and changes the log level of the default instance.
+//
+// GET returns the current level. PUT changes it; an optional ttl reverts the change
+// automatically, which is useful to enable debug logging during an incident:
+//
+//	mux.HandleFunc("/loglevel", telemetry.LogLevelEndpointHandler)
+//
+//	curl -X PUT -d '{"level": "debug", "ttl": "15m"}' http://localhost:8081/loglevel
+func LogLevelEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	Default().LogLevelEndpointHandler(w, r)
+}
+
+// LogLevelEndpointHandler reads and changes the log level of t.
+func (t *Telemetry) LogLevelEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req logLevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+			http.Error(w, fmt.Sprintf("invalid log level: %v", err), http.StatusBadRequest)
+			return
+		}
+		var ttl time.Duration
+		if req.TTL != "" {
+			var err error
+			if ttl, err = time.ParseDuration(req.TTL); err != nil {
+				http.Error(w, fmt.Sprintf("invalid ttl: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+		t.level.set(level, ttl, func(level slog.Level) {
+			t.Logger().Info("Log level reverted", "level", level)
+		})
+		t.Logger().Info("Log level changed", "level", level, "ttl", ttl)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(t.level.status())
+}
@@ -0,0 +1,168 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func doLogLevelRequest(t *testing.T, method string, body string) (*httptest.ResponseRecorder, logLevelResponse) {
+	t.Helper()
+	req := httptest.NewRequest(method, "/loglevel", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	LogLevelEndpointHandler(w, req)
+
+	var resp logLevelResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w, resp
+}
+
+func TestLogLevelEndpointHandler_Get(t *testing.T) {
+	shutdown, err := Init("loglevel-test", "test", WithSlog(SlogLogLevel(slog.LevelWarn)))
+	require.NoError(t, err)
+	defer func() { _ = shutdown(context.Background()) }()
+
+	w, resp := doLogLevelRequest(t, http.MethodGet, "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "WARN", resp.Level)
+	assert.Empty(t, resp.RevertAt)
+}
+
+func TestLogLevelEndpointHandler_Put(t *testing.T) {
+	shutdown, err := Init("loglevel-test", "test", WithSlog())
+	require.NoError(t, err)
+	defer func() { _ = shutdown(context.Background()) }()
+
+	ctx := context.Background()
+	assert.False(t, slog.Default().Enabled(ctx, slog.LevelDebug))
+
+	w, resp := doLogLevelRequest(t, http.MethodPut, `{"level": "debug"}`)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "DEBUG", resp.Level)
+	assert.True(t, slog.Default().Enabled(ctx, slog.LevelDebug))
+}
+
+func TestLogLevelEndpointHandler_PutWithTTLReverts(t *testing.T) {
+	shutdown, err := Init("loglevel-test", "test", WithSlog())
+	require.NoError(t, err)
+	defer func() { _ = shutdown(context.Background()) }()
+
+	w, resp := doLogLevelRequest(t, http.MethodPut, `{"level": "debug", "ttl": "50ms"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "DEBUG", resp.Level)
+	assert.Equal(t, "INFO", resp.RevertTo)
+	assert.NotEmpty(t, resp.RevertAt)
+
+	// A second change while the first is pending keeps the original revert target
+	_, resp = doLogLevelRequest(t, http.MethodPut, `{"level": "error", "ttl": "50ms"}`)
+	assert.Equal(t, "ERROR", resp.Level)
+	assert.Equal(t, "INFO", resp.RevertTo)
+
+	assert.Eventually(t, func() bool {
+		return Default().level.level.Level() == slog.LevelInfo
+	}, time.Second, 10*time.Millisecond)
+
+	_, resp = doLogLevelRequest(t, http.MethodGet, "")
+	assert.Equal(t, "INFO", resp.Level)
+	assert.Empty(t, resp.RevertAt)
+}
+
+func TestLogLevelEndpointHandler_InstancesAreIndependent(t *testing.T) {
+	first, err := New("loglevel-first", "test", WithSlog(SlogOutput(io.Discard)))
+	require.NoError(t, err)
+	defer func() { _ = first.Shutdown(context.Background()) }()
+	second, err := New("loglevel-second", "test", WithSlog(SlogOutput(io.Discard)))
+	require.NoError(t, err)
+	defer func() { _ = second.Shutdown(context.Background()) }()
+
+	req := httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(`{"level": "debug", "ttl": "1h"}`))
+	first.LogLevelEndpointHandler(httptest.NewRecorder(), req)
+
+	ctx := context.Background()
+	assert.True(t, first.Logger().Enabled(ctx, slog.LevelDebug))
+	assert.False(t, second.Logger().Enabled(ctx, slog.LevelDebug))
+	assert.Empty(t, second.level.status().RevertAt)
+}
+
+func TestLogLevelEndpointHandler_BadRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		want   int
+	}{
+		{"invalid json", http.MethodPut, `{`, http.StatusBadRequest},
+		{"invalid level", http.MethodPut, `{"level": "verbose"}`, http.StatusBadRequest},
+		{"invalid ttl", http.MethodPut, `{"level": "debug", "ttl": "soon"}`, http.StatusBadRequest},
+		{"unsupported method", http.MethodPost, `{"level": "debug"}`, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, _ := doLogLevelRequest(t, tt.method, tt.body)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for the timer goroutine to write to.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLogLevelEndpointHandler_RevertLogsToInstance(t *testing.T) {
+	var out syncBuffer
+	tel, err := New("loglevel-test", "test", WithSlog(SlogOutput(&out)))
+	require.NoError(t, err)
+	defer func() { _ = tel.Shutdown(context.Background()) }()
+
+	req := httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(`{"level": "debug", "ttl": "20ms"}`))
+	tel.LogLevelEndpointHandler(httptest.NewRecorder(), req)
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "Log level reverted")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestShutdown_CancelsLevelRevert(t *testing.T) {
+	tel, err := New("loglevel-test", "test", WithSlog(SlogOutput(io.Discard)))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(`{"level": "debug", "ttl": "20ms"}`))
+	tel.LogLevelEndpointHandler(httptest.NewRecorder(), req)
+	require.NotEmpty(t, tel.level.status().RevertAt)
+
+	require.NoError(t, tel.Shutdown(context.Background()))
+	assert.Empty(t, tel.level.status().RevertAt)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, slog.LevelDebug, tel.level.level.Level())
+}
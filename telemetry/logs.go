@@ -0,0 +1,121 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"google.golang.org/grpc/credentials"
+)
+
+// initLogExport builds the LoggerProvider with an OTLP exporter and a batch processor.
+// Without LogExportURL, the exporter shares the endpoint and transport settings of the
+// trace exporter.
+func initLogExport(ctx context.Context, cfg *Config, res *resource.Resource) (*sdklog.LoggerProvider, error) {
+	transport := traceConfig{ExporterURL: cfg.LogExportConfig.ExporterURL}
+	if transport.ExporterURL == "" {
+		var err error
+		if transport, err = cfg.TraceConfig.sharedTransport("logs"); err != nil {
+			return nil, err
+		}
+	}
+
+	opts, err := logExporterOptions(transport)
+	if err != nil {
+		return nil, fmt.Errorf("otel log exporter init failed: %w", err)
+	}
+	exporter, err := otlploggrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("otel log exporter init failed: %w", err)
+	}
+
+	lp := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+		sdklog.WithResource(res),
+	)
+	return lp, nil
+}
+
+// logExporterOptions returns the OTLP/gRPC options for the endpoint, TLS, headers,
+// compression and timeout of cfg, like newTraceExporter does for spans.
+func logExporterOptions(cfg traceConfig) ([]otlploggrpc.Option, error) {
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	withURL := isEndpointURL(cfg.ExporterURL)
+
+	opts := []otlploggrpc.Option{}
+	if withURL {
+		opts = append(opts, otlploggrpc.WithEndpointURL(cfg.ExporterURL))
+	} else {
+		opts = append(opts, otlploggrpc.WithEndpoint(cfg.ExporterURL))
+	}
+	if tlsConfig != nil {
+		opts = append(opts, otlploggrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+	} else if !withURL {
+		opts = append(opts, otlploggrpc.WithInsecure())
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlploggrpc.WithHeaders(cfg.Headers))
+	}
+	if cfg.Compression == "gzip" {
+		opts = append(opts, otlploggrpc.WithCompressor(cfg.Compression))
+	}
+	if cfg.Timeout > 0 {
+		opts = append(opts, otlploggrpc.WithTimeout(cfg.Timeout))
+	}
+	return opts, nil
+}
+
+// newLogExportHandler returns a slog handler that emits records to the LoggerProvider.
+// The trace and span IDs are taken from the context passed to the logger.
+func newLogExportHandler(serviceName string, lp *sdklog.LoggerProvider) slog.Handler {
+	return otelslog.NewHandler(serviceName, otelslog.WithLoggerProvider(lp))
+}
+
+// fanoutHandler sends each slog record to all of its handlers.
+// Records below level are dropped before they reach any handler.
+type fanoutHandler struct {
+	level    slog.Leveler
+	handlers []slog.Handler
+}
+
+func newFanoutHandler(level slog.Leveler, handlers ...slog.Handler) *fanoutHandler {
+	return &fanoutHandler{level: level, handlers: handlers}
+}
+
+func (h *fanoutHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, r.Level) {
+			errs = append(errs, handler.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return newFanoutHandler(h.level, handlers...)
+}
+
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return newFanoutHandler(h.level, handlers...)
+}
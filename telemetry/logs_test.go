@@ -0,0 +1,90 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// recordingLogExporter keeps exported log records in memory.
+type recordingLogExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (e *recordingLogExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range records {
+		e.records = append(e.records, r.Clone())
+	}
+	return nil
+}
+
+func (e *recordingLogExporter) Shutdown(context.Context) error   { return nil }
+func (e *recordingLogExporter) ForceFlush(context.Context) error { return nil }
+
+func TestInit_WithLogExport_MissingExporterURL(t *testing.T) {
+	shutdown, err := Init(
+		"test-service",
+		"test",
+		WithLogExport(), // No exporter URL and no trace exporter to fall back to
+	)
+
+	require.Error(t, err)
+	assert.Nil(t, shutdown)
+	assert.Contains(t, err.Error(), "logs exporter URL is required")
+}
+
+func TestFanoutHandler_SendsToAllHandlers(t *testing.T) {
+	var first, second bytes.Buffer
+	handler := newFanoutHandler(
+		slog.LevelInfo,
+		slog.NewTextHandler(&first, nil),
+		slog.NewJSONHandler(&second, nil),
+	)
+	logger := slog.New(handler).With("component", "fanout")
+
+	logger.Debug("dropped below level")
+	logger.Info("hello")
+
+	assert.Contains(t, first.String(), "msg=hello component=fanout")
+	assert.Contains(t, second.String(), `"msg":"hello","component":"fanout"`)
+	assert.NotContains(t, first.String(), "dropped")
+	assert.NotContains(t, second.String(), "dropped")
+}
+
+func TestLogExportHandler_KeepsTraceCorrelation(t *testing.T) {
+	exporter := &recordingLogExporter{}
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	defer func() { _ = lp.Shutdown(context.Background()) }()
+
+	var stdout bytes.Buffer
+	handler := newOTelHandler(newFanoutHandler(
+		slog.LevelInfo,
+		slog.NewTextHandler(&stdout, nil),
+		newLogExportHandler("log-export-test", lp),
+	))
+
+	tp := trace.NewTracerProvider()
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "log-span")
+	defer span.End()
+
+	slog.New(handler).InfoContext(ctx, "order processed", "order_id", "42")
+
+	require.Len(t, exporter.records, 1)
+	record := exporter.records[0]
+	assert.Equal(t, "order processed", record.Body().AsString())
+	assert.Equal(t, span.SpanContext().TraceID(), record.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), record.SpanID())
+
+	assert.Contains(t, stdout.String(), "trace_id="+span.SpanContext().TraceID().String())
+}
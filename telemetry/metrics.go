@@ -0,0 +1,121 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"google.golang.org/grpc/credentials"
+)
+
+// initMetrics builds the MeterProvider with an OTLP exporter and a periodic reader.
+// Without MetricsExporterURL, the exporter shares the endpoint and transport settings of
+// the trace exporter.
+// With MetricsPrometheus, a Prometheus reader is added, its registry is served by
+// t.MetricsEndpointHandler and the OTLP exporter becomes optional.
+func (t *Telemetry) initMetrics(ctx context.Context, res *resource.Resource) (*sdkmetric.MeterProvider, error) {
+	cfg := &t.cfg
+	logger := t.Logger()
+	mpOpts := []sdkmetric.Option{sdkmetric.WithResource(res)}
+
+	if cfg.MetricsConfig.Prometheus {
+		registry := prometheus.NewRegistry()
+		exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+		if err != nil {
+			logger.Error("prometheus exporter init failed", "err", err)
+			return nil, err
+		}
+		mpOpts = append(mpOpts, sdkmetric.WithReader(exporter))
+		t.metricsHandler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+		logger.Info("Prometheus metrics endpoint initialized")
+	}
+
+	transport := traceConfig{ExporterURL: cfg.MetricsConfig.ExporterURL}
+	if transport.ExporterURL == "" && !cfg.MetricsConfig.Prometheus {
+		var err error
+		if transport, err = cfg.TraceConfig.sharedTransport("metrics"); err != nil {
+			logger.Error("OpenTelemetry metrics exporter init failed", "err", err)
+			return nil, err
+		}
+	}
+
+	if transport.ExporterURL != "" {
+		opts, err := metricExporterOptions(transport)
+		if err != nil {
+			logger.Error("otel metric exporter init failed", "err", err)
+			return nil, err
+		}
+		exporter, err := otlpmetricgrpc.New(ctx, opts...)
+		if err != nil {
+			logger.Error("otel metric exporter init failed", "err", err)
+			return nil, err
+		}
+
+		var readerOpts []sdkmetric.PeriodicReaderOption
+		if cfg.MetricsConfig.Interval > 0 {
+			readerOpts = append(readerOpts, sdkmetric.WithInterval(cfg.MetricsConfig.Interval))
+		}
+		mpOpts = append(mpOpts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, readerOpts...)))
+		logger.Info("OpenTelemetry metrics exporter initialized", "url", redactEndpoint(transport.ExporterURL))
+	}
+
+	return sdkmetric.NewMeterProvider(mpOpts...), nil
+}
+
+// metricExporterOptions returns the OTLP/gRPC options for the endpoint, TLS, headers,
+// compression and timeout of cfg, like newTraceExporter does for spans.
+func metricExporterOptions(cfg traceConfig) ([]otlpmetricgrpc.Option, error) {
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	withURL := isEndpointURL(cfg.ExporterURL)
+
+	opts := []otlpmetricgrpc.Option{}
+	if withURL {
+		opts = append(opts, otlpmetricgrpc.WithEndpointURL(cfg.ExporterURL))
+	} else {
+		opts = append(opts, otlpmetricgrpc.WithEndpoint(cfg.ExporterURL))
+	}
+	if tlsConfig != nil {
+		opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+	} else if !withURL {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlpmetricgrpc.WithHeaders(cfg.Headers))
+	}
+	if cfg.Compression == "gzip" {
+		opts = append(opts, otlpmetricgrpc.WithCompressor(cfg.Compression))
+	}
+	if cfg.Timeout > 0 {
+		opts = append(opts, otlpmetricgrpc.WithTimeout(cfg.Timeout))
+	}
+	return opts, nil
+}
+
+// MetricsEndpointHandler serves the OpenTelemetry metrics in the Prometheus text format.
+// It can be mounted on the same mux as HealthzEndpointHandler:
+//
+//	mux.HandleFunc("/healthz", telemetry.HealthzEndpointHandler)
+//	mux.HandleFunc("/metrics", telemetry.MetricsEndpointHandler)
+//
+// It serves the default instance and responds with 404 unless Init was called with
+// WithMetrics(MetricsPrometheus()).
+func MetricsEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	Default().MetricsEndpointHandler(w, r)
+}
+
+// MetricsEndpointHandler serves the Prometheus registry of t.
+func (t *Telemetry) MetricsEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	if t.metricsHandler == nil {
+		http.Error(w, "Prometheus metrics are not enabled", http.StatusNotFound)
+		return
+	}
+	t.metricsHandler.ServeHTTP(w, r)
+}
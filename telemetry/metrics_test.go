@@ -0,0 +1,163 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestInit_WithMetrics_MissingExporterURL(t *testing.T) {
+	shutdown, err := Init(
+		"test-service",
+		"test",
+		WithMetrics(), // No exporter URL and no trace exporter to fall back to
+	)
+
+	require.Error(t, err)
+	assert.Nil(t, shutdown)
+	assert.Contains(t, err.Error(), "metrics exporter URL is required")
+}
+
+func TestSetDefault_RegistersGlobalMeterProvider(t *testing.T) {
+	cfg := &Config{ServiceName: "metrics-test", Environment: "test"}
+	WithMetrics(
+		MetricsExporterURL("127.0.0.1:9999"),
+		MetricsInterval(time.Hour),
+	)(cfg)
+
+	tel := useDefault(t, *cfg)
+	mp, err := tel.initMetrics(context.Background(), newResource(cfg))
+	require.NoError(t, err)
+	require.NotNil(t, mp)
+	tel.meterProvider = mp
+
+	// Nothing is listening, so don't wait for the final export
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	defer func() { _ = mp.Shutdown(ctx) }()
+
+	// Building the provider leaves the global alone until SetDefault
+	assert.NotEqual(t, mp, otel.GetMeterProvider())
+	SetDefault(tel)
+	assert.Equal(t, mp, otel.GetMeterProvider())
+}
+
+func TestInitMetrics_FallsBackToTraceExporterURL(t *testing.T) {
+	cfg := &Config{ServiceName: "metrics-test", Environment: "test"}
+	WithTrace(TraceExporterURL("127.0.0.1:9999"))(cfg)
+	WithMetrics()(cfg)
+
+	mp, err := newTelemetry(*cfg).initMetrics(context.Background(), newResource(cfg))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = mp.Shutdown(ctx)
+}
+
+func TestNew_MetricsAndLogsShareTraceTransport(t *testing.T) {
+	collector, metrics, logs := newFakeCollector(t)
+
+	tel, err := New("transport-test", "test",
+		WithTrace(
+			TraceExporterURL(collector),
+			TraceHeaders(map[string]string{"x-api-key": "secret"}),
+			TraceCompression("gzip"),
+			TraceTimeout(5*time.Second),
+		),
+		WithMetrics(),
+		WithLogExport(),
+	)
+	require.NoError(t, err)
+	defer func() { _ = tel.Shutdown(context.Background()) }()
+
+	counter, err := tel.MeterProvider().Meter("transport-test").Int64Counter("orders")
+	require.NoError(t, err)
+	counter.Add(context.Background(), 1)
+	tel.Logger().Info("order placed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, tel.meterProvider.ForceFlush(ctx))
+	require.NoError(t, tel.loggerProvider.ForceFlush(ctx))
+	require.NotEmpty(t, metrics.received)
+	assert.Equal(t, []string{"secret"}, (<-metrics.received).Get("x-api-key"))
+	require.NotEmpty(t, logs.received)
+	assert.Equal(t, []string{"secret"}, (<-logs.received).Get("x-api-key"))
+}
+
+func TestNew_HTTPTraceTransportNotShared(t *testing.T) {
+	trace := WithTrace(TraceExporterURL("collector:4318"), TraceProtocol(ProtocolHTTPProtobuf))
+
+	_, err := New("transport-test", "test", trace, WithMetrics())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "metrics exporter URL is required when traces are exported over http/protobuf")
+
+	_, err = New("transport-test", "test", trace, WithLogExport())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "logs exporter URL is required when traces are exported over http/protobuf")
+}
+
+func TestNewResource_Attributes(t *testing.T) {
+	cfg := &Config{ServiceName: "resource-test", Environment: "staging"}
+	WithSentry(SentryRelease("v1.2.3"))(cfg)
+
+	attrs := map[string]string{}
+	for _, kv := range newResource(cfg).Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+
+	assert.Equal(t, "resource-test", attrs["service.name"])
+	assert.Equal(t, "staging", attrs["deployment.environment"])
+	assert.Equal(t, "v1.2.3", attrs["service.version"])
+}
+
+func TestMetricsEndpointHandler_NotEnabled(t *testing.T) {
+	useDefault(t, Config{})
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	MetricsEndpointHandler(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMetricsEndpointHandler_Prometheus(t *testing.T) {
+	shutdown, err := Init(
+		"prometheus-test",
+		"test",
+		WithMetrics(MetricsPrometheus()), // No OTLP exporter needed
+	)
+	require.NoError(t, err)
+	defer func() { _ = shutdown(context.Background()) }()
+
+	counter, err := otel.Meter("test").Int64Counter("orders_processed")
+	require.NoError(t, err)
+	counter.Add(context.Background(), 3)
+
+	// Health and metrics share one admin mux
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", HealthzEndpointHandler)
+	mux.HandleFunc("/metrics", MetricsEndpointHandler)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "orders_processed_total")
+	assert.Contains(t, w.Body.String(), `service_name="prometheus-test"`)
+
+	req = httptest.NewRequest("GET", "/healthz", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
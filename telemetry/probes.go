@@ -0,0 +1,84 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+)
+
+// Probe names a Kubernetes probe that a check participates in.
+type Probe string
+
+const (
+	// ProbeLiveness restarts the pod when it fails. Only checks for unrecoverable states
+	// belong here, e.g. a deadlocked worker.
+	ProbeLiveness Probe = "liveness"
+	// ProbeReadiness takes the pod out of rotation while it fails.
+	ProbeReadiness Probe = "readiness"
+	// ProbeStartup holds back the other probes until it succeeds once.
+	ProbeStartup Probe = "startup"
+)
+
+// StartDraining marks the default instance as draining. See Telemetry.StartDraining.
+func StartDraining() {
+	Default().StartDraining()
+}
+
+// StartDraining makes the readiness probe fail so Kubernetes stops routing traffic to the
+// pod, while the liveness probe stays green. Call it when graceful shutdown begins, before
+// stopping the HTTP server; Shutdown calls it as well.
+func (t *Telemetry) StartDraining() {
+	if t.health.draining.CompareAndSwap(false, true) {
+		t.Logger().Info("Draining, readiness probe now fails")
+	}
+}
+
+// Draining reports whether StartDraining was called on t.
+func (t *Telemetry) Draining() bool {
+	return t.health.draining.Load()
+}
+
+// CheckProbe runs the checks that participate in probe concurrently and returns their
+// report. While t is draining, the readiness report fails without running any check.
+func (t *Telemetry) CheckProbe(ctx context.Context, probe Probe) HealthReport {
+	if probe == ProbeReadiness && t.Draining() {
+		return HealthReport{Status: HealthStatusFail, Draining: true, Checks: []CheckResult{}}
+	}
+	return runChecks(ctx, t.checks.forProbe(probe))
+}
+
+// LivezEndpointHandler serves the liveness probe of the default instance.
+func LivezEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	Default().LivezEndpointHandler(w, r)
+}
+
+// ReadyzEndpointHandler serves the readiness probe of the default instance.
+func ReadyzEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	Default().ReadyzEndpointHandler(w, r)
+}
+
+// StartupzEndpointHandler serves the startup probe of the default instance.
+func StartupzEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	Default().StartupzEndpointHandler(w, r)
+}
+
+// LivezEndpointHandler runs the checks registered with ProbeLiveness. With none, it only
+// shows that the process is serving requests.
+func (t *Telemetry) LivezEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	t.serveProbe(w, r, ProbeLiveness)
+}
+
+// ReadyzEndpointHandler runs the checks registered with ProbeReadiness, which includes
+// the built-in MySQL and NATS checks. It fails while t is draining.
+func (t *Telemetry) ReadyzEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	t.serveProbe(w, r, ProbeReadiness)
+}
+
+// StartupzEndpointHandler runs the checks registered with ProbeStartup.
+func (t *Telemetry) StartupzEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	t.serveProbe(w, r, ProbeStartup)
+}
+
+// serveProbe writes the report of probe like HealthzEndpointHandler does.
+func (t *Telemetry) serveProbe(w http.ResponseWriter, r *http.Request, probe Probe) {
+	t.writeHealthReport(w, t.CheckProbe(r.Context(), probe))
+}
@@ -0,0 +1,84 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registerProbeChecks registers a failing readiness check and passing liveness and startup checks.
+func registerProbeChecks(tel *Telemetry) {
+	fail := CheckerFunc(func(_ context.Context) error { return errors.New("down") })
+	pass := CheckerFunc(func(_ context.Context) error { return nil })
+	tel.RegisterCheck("database", fail) // Readiness and startup by default
+	tel.RegisterCheck("worker", pass, CheckProbes(ProbeLiveness))
+	tel.RegisterCheck("migrations", pass, CheckProbes(ProbeStartup))
+}
+
+func TestProbes_SelectChecks(t *testing.T) {
+	tel := newTelemetry(Config{})
+	registerProbeChecks(tel)
+
+	code, report := getHealthz(t, tel.LivezEndpointHandler)
+	assert.Equal(t, http.StatusOK, code)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, "worker", report.Checks[0].Name)
+
+	code, report = getHealthz(t, tel.ReadyzEndpointHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, "database", report.Checks[0].Name)
+
+	code, report = getHealthz(t, tel.StartupzEndpointHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Len(t, report.Checks, 2)
+}
+
+func TestProbes_BuiltinChecksSkipLiveness(t *testing.T) {
+	// A MySQL outage must not get the pod restarted
+	useDefault(t, Config{MysqlEnabled: true, MysqlConfig: mySQLConfig{DSN: "invalid-mysql-dsn"}})
+
+	code, report := getHealthz(t, LivezEndpointHandler)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, report.Checks)
+
+	code, report = getHealthz(t, ReadyzEndpointHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "mysql", report.Checks[0].Name)
+
+	code, _ = getHealthz(t, StartupzEndpointHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestProbes_Draining(t *testing.T) {
+	useDefault(t, Config{})
+	RegisterCheck("worker", CheckerFunc(func(_ context.Context) error { return nil }),
+		CheckProbes(ProbeLiveness, ProbeReadiness))
+
+	code, report := getHealthz(t, ReadyzEndpointHandler)
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, report.Draining)
+
+	StartDraining()
+	assert.True(t, Default().Draining())
+
+	code, report = getHealthz(t, ReadyzEndpointHandler)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.True(t, report.Draining)
+	assert.Empty(t, report.Checks)
+
+	code, _ = getHealthz(t, LivezEndpointHandler)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestShutdown_StartsDraining(t *testing.T) {
+	tel := newTelemetry(Config{})
+
+	require.NoError(t, tel.Shutdown(context.Background()))
+
+	assert.True(t, tel.Draining())
+}
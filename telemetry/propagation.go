@@ -0,0 +1,82 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+
+	"github.com/getsentry/sentry-go"
+	sentryotel "github.com/getsentry/sentry-go/otel"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// sentrySpanProcessor is created once because every call to sentryotel.NewSentrySpanProcessor
+// registers another global Sentry event processor.
+var sentrySpanProcessor = sync.OnceValue(sentryotel.NewSentrySpanProcessor)
+
+// newPropagator returns the W3C trace context and baggage propagator, plus the
+// sentry-trace header when Sentry is enabled.
+func newPropagator(sentryEnabled bool) propagation.TextMapPropagator {
+	propagators := []propagation.TextMapPropagator{
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	}
+	if sentryEnabled {
+		propagators = append(propagators, sentryTracePropagator{sentry: sentryotel.NewSentryPropagator()})
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...)
+}
+
+// sentryTracePropagator wraps the Sentry propagator so its baggage is merged into the
+// W3C baggage header instead of replacing it.
+type sentryTracePropagator struct {
+	sentry propagation.TextMapPropagator
+}
+
+func (p sentryTracePropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sentryCarrier := propagation.MapCarrier{}
+	p.sentry.Inject(ctx, sentryCarrier)
+
+	if sentryTrace := sentryCarrier.Get(sentry.SentryTraceHeader); sentryTrace != "" {
+		carrier.Set(sentry.SentryTraceHeader, sentryTrace)
+	}
+	if sentryBaggage := sentryCarrier.Get(sentry.SentryBaggageHeader); sentryBaggage != "" {
+		carrier.Set(
+			sentry.SentryBaggageHeader,
+			mergeBaggage(carrier.Get(sentry.SentryBaggageHeader), sentryBaggage),
+		)
+	}
+}
+
+func (p sentryTracePropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return p.sentry.Extract(ctx, carrier)
+}
+
+func (p sentryTracePropagator) Fields() []string {
+	return p.sentry.Fields()
+}
+
+// mergeBaggage adds the members of extra that are missing from existing.
+// Members already present in existing are kept as they are.
+func mergeBaggage(existing string, extra string) string {
+	if existing == "" {
+		return extra
+	}
+	merged, err := baggage.Parse(existing)
+	if err != nil {
+		return extra
+	}
+	extraBaggage, err := baggage.Parse(extra)
+	if err != nil {
+		return existing
+	}
+	for _, member := range extraBaggage.Members() {
+		if merged.Member(member.Key()).Key() != "" {
+			continue
+		}
+		if withMember, err := merged.SetMember(member); err == nil {
+			merged = withMember
+		}
+	}
+	return merged.String()
+}
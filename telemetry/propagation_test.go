@@ -0,0 +1,116 @@
+package telemetry
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func TestInit_WithSentryAndTrace_SharesTracerProvider(t *testing.T) {
+	shutdown, err := Init(
+		"unified-test",
+		"test",
+		WithSentry(SentryDSN("https://test@o123456.ingest.us.sentry.io/123456")),
+		WithTrace(TraceExporterURL("127.0.0.1:9999")),
+	)
+	require.NoError(t, err)
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "unified-span")
+	defer span.End()
+	// Shut down before the span ends so nothing is exported to the unreachable collector
+	defer func() { _ = shutdown(context.Background()) }()
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	// traceparent comes from the OTLP side, sentry-trace only exists when the
+	// Sentry span processor saw the same span
+	traceID := span.SpanContext().TraceID().String()
+	assert.Contains(t, carrier.Get("traceparent"), traceID)
+	assert.True(t, strings.HasPrefix(carrier.Get("sentry-trace"), traceID))
+}
+
+func TestNewPropagator_Fields(t *testing.T) {
+	assert.ElementsMatch(t,
+		[]string{"traceparent", "tracestate", "baggage"},
+		newPropagator(false).Fields(),
+	)
+	assert.ElementsMatch(t,
+		[]string{"traceparent", "tracestate", "baggage", "sentry-trace"},
+		newPropagator(true).Fields(),
+	)
+}
+
+func TestNewPropagator_SentryKeepsW3CBaggage(t *testing.T) {
+	member, err := baggage.NewMember("tenant_id", "acme")
+	require.NoError(t, err)
+	bag, err := baggage.New(member)
+	require.NoError(t, err)
+	ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+	// An upstream service sent Sentry's dynamic sampling context
+	ctx = newPropagator(true).Extract(ctx, propagation.MapCarrier{
+		"baggage": "sentry-trace_id=0123456789abcdef0123456789abcdef,sentry-public_key=abc",
+	})
+	ctx = baggage.ContextWithBaggage(ctx, bag)
+
+	carrier := propagation.MapCarrier{}
+	newPropagator(true).Inject(ctx, carrier)
+
+	out, err := baggage.Parse(carrier.Get("baggage"))
+	require.NoError(t, err)
+	assert.Equal(t, "acme", out.Member("tenant_id").Value())
+	assert.Equal(t, "abc", out.Member("sentry-public_key").Value())
+}
+
+func TestMergeBaggage(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		extra    string
+		want     map[string]string
+	}{
+		{
+			name:  "no existing baggage",
+			extra: "sentry-release=v1",
+			want:  map[string]string{"sentry-release": "v1"},
+		},
+		{
+			name:     "adds missing members",
+			existing: "tenant_id=acme",
+			extra:    "sentry-release=v1",
+			want:     map[string]string{"tenant_id": "acme", "sentry-release": "v1"},
+		},
+		{
+			name:     "existing members win",
+			existing: "sentry-release=v2",
+			extra:    "sentry-release=v1",
+			want:     map[string]string{"sentry-release": "v2"},
+		},
+		{
+			name:     "invalid extra keeps existing",
+			existing: "tenant_id=acme",
+			extra:    "=",
+			want:     map[string]string{"tenant_id": "acme"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := baggage.Parse(mergeBaggage(tt.existing, tt.extra))
+			require.NoError(t, err)
+
+			got := map[string]string{}
+			for _, m := range merged.Members() {
+				got[m.Key()] = m.Value()
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package telemetry
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// maxCaptureBuckets is the number of fingerprints above which idle buckets are dropped.
+const maxCaptureBuckets = 1000
+
+// CaptureLimitOption defines a function type for configuring capture rate limiting.
+type CaptureLimitOption func(*captureLimitConfig)
+
+type captureLimitConfig struct {
+	Burst        int
+	Interval     time.Duration
+	DedupeWindow time.Duration
+}
+
+// WithCaptureLimit limits how many events CaptureError and CaptureMessage send to Sentry
+// and the log, so a hot loop during an outage can't flood either. Each fingerprint (the
+// CaptureFingerprint parts, or the message) gets a token bucket of 10 events refilled at
+// 10 per minute, and identical events within 10 seconds are sent once. Spans are still
+// recorded for every call. The number of suppressed events is attached to the next event
+// that gets through and counted in the "telemetry.capture.suppressed" metric.
+func WithCaptureLimit(opts ...CaptureLimitOption) Option {
+	return func(cfg *Config) {
+		cfg.CaptureLimitEnabled = true
+		clc := captureLimitConfig{
+			Burst:        10,
+			Interval:     6 * time.Second,
+			DedupeWindow: 10 * time.Second,
+		}
+		for _, opt := range opts {
+			opt(&clc)
+		}
+		cfg.CaptureLimitConfig = clc
+	}
+}
+
+// CaptureLimitRate allows burst events per fingerprint at once, refilled at burst per period.
+func CaptureLimitRate(burst int, period time.Duration) CaptureLimitOption {
+	return func(cfg *captureLimitConfig) {
+		if burst > 0 && period > 0 {
+			cfg.Burst = burst
+			cfg.Interval = period / time.Duration(burst)
+		}
+	}
+}
+
+// CaptureDedupeWindow sets how long identical events are collapsed into one. Zero
+// disables deduplication.
+func CaptureDedupeWindow(window time.Duration) CaptureLimitOption {
+	return func(cfg *captureLimitConfig) { cfg.DedupeWindow = window }
+}
+
+// captureLimiter applies the token buckets and the dedupe window.
+type captureLimiter struct {
+	cfg captureLimitConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*captureBucket
+
+	rateLimited atomic.Int64
+	duplicates  atomic.Int64
+}
+
+type captureBucket struct {
+	tokens     float64
+	updated    time.Time
+	sent       map[string]time.Time // When each distinct event was last sent
+	suppressed int
+}
+
+func newCaptureLimiter(cfg captureLimitConfig) *captureLimiter {
+	return &captureLimiter{
+		cfg:     cfg,
+		now:     time.Now,
+		buckets: map[string]*captureBucket{},
+	}
+}
+
+// allow reports whether the event identified by key may be sent for fingerprint. When it
+// may, it also returns how many events of fingerprint were suppressed since the last one.
+// A nil limiter allows everything.
+func (l *captureLimiter) allow(fingerprint, key string) (bool, int) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[fingerprint]
+	if !ok {
+		if len(l.buckets) >= maxCaptureBuckets {
+			l.prune(now)
+		}
+		b = &captureBucket{tokens: float64(l.cfg.Burst), updated: now, sent: map[string]time.Time{}}
+		l.buckets[fingerprint] = b
+	}
+
+	for k, sent := range b.sent {
+		if now.Sub(sent) >= l.cfg.DedupeWindow {
+			delete(b.sent, k)
+		}
+	}
+	if _, dup := b.sent[key]; dup {
+		b.suppressed++
+		l.duplicates.Add(1)
+		return false, 0
+	}
+
+	b.tokens = min(float64(l.cfg.Burst), b.tokens+float64(now.Sub(b.updated))/float64(l.cfg.Interval))
+	b.updated = now
+	if b.tokens < 1 {
+		b.suppressed++
+		l.rateLimited.Add(1)
+		return false, 0
+	}
+	b.tokens--
+	if l.cfg.DedupeWindow > 0 {
+		b.sent[key] = now
+	}
+	suppressed := b.suppressed
+	b.suppressed = 0
+	return true, suppressed
+}
+
+// prune drops the buckets that are full again and have nothing to report.
+func (l *captureLimiter) prune(now time.Time) {
+	idle := time.Duration(l.cfg.Burst)*l.cfg.Interval + l.cfg.DedupeWindow
+	for fingerprint, b := range l.buckets {
+		if b.suppressed == 0 && now.Sub(b.updated) >= idle {
+			delete(l.buckets, fingerprint)
+		}
+	}
+}
+
+// limitKeys returns the fingerprint and the event key CaptureError and CaptureMessage
+// limit on.
+func (cfg *captureConfig) limitKeys(message, detail string) (string, string) {
+	fingerprint := message
+	if len(cfg.fingerprint) > 0 {
+		fingerprint = strings.Join(cfg.fingerprint, "\x1f")
+	}
+	return fingerprint, string(cfg.level) + "\x1f" + message + "\x1f" + detail
+}
+
+// registerCaptureMetrics reports the suppressed events of t's limiter on mp.
+func (t *Telemetry) registerCaptureMetrics(mp metric.MeterProvider) error {
+	l := t.limiter
+	if l == nil {
+		return nil
+	}
+	rateLimited := metric.WithAttributeSet(attribute.NewSet(attribute.String("reason", "rate_limit")))
+	duplicate := metric.WithAttributeSet(attribute.NewSet(attribute.String("reason", "duplicate")))
+	_, err := mp.Meter(instrumentationName).Int64ObservableCounter(
+		"telemetry.capture.suppressed",
+		metric.WithDescription("Events dropped by the CaptureError and CaptureMessage rate limit"),
+		metric.WithUnit("{event}"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(l.rateLimited.Load(), rateLimited)
+			o.Observe(l.duplicates.Load(), duplicate)
+			return nil
+		}),
+	)
+	return err
+}
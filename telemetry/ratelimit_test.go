@@ -0,0 +1,134 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// fakeClock is a settable time source for the capture limiter.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestLimiter(opts ...CaptureLimitOption) (*captureLimiter, *fakeClock) {
+	var cfg Config
+	WithCaptureLimit(opts...)(&cfg)
+	l := newCaptureLimiter(cfg.CaptureLimitConfig)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l.now = clock.Now
+	return l, clock
+}
+
+func TestCaptureLimiter_TokenBucket(t *testing.T) {
+	l, clock := newTestLimiter(CaptureLimitRate(2, time.Minute), CaptureDedupeWindow(0))
+
+	for i := range 2 {
+		ok, _ := l.allow("order failed", fmt.Sprint(i))
+		assert.True(t, ok)
+	}
+	ok, _ := l.allow("order failed", "3")
+	assert.False(t, ok)
+	ok, _ = l.allow("order failed", "4")
+	assert.False(t, ok)
+
+	// Other fingerprints have their own bucket
+	ok, _ = l.allow("payment failed", "1")
+	assert.True(t, ok)
+
+	// One token every 30s
+	clock.Advance(30 * time.Second)
+	ok, suppressed := l.allow("order failed", "5")
+	assert.True(t, ok)
+	assert.Equal(t, 2, suppressed)
+	ok, _ = l.allow("order failed", "6")
+	assert.False(t, ok)
+	assert.Equal(t, int64(3), l.rateLimited.Load())
+}
+
+func TestCaptureLimiter_DedupeWindow(t *testing.T) {
+	l, clock := newTestLimiter(CaptureDedupeWindow(10 * time.Second))
+
+	ok, _ := l.allow("order failed", "timeout")
+	assert.True(t, ok)
+	ok, _ = l.allow("order failed", "timeout")
+	assert.False(t, ok)
+	ok, suppressed := l.allow("order failed", "refused")
+	assert.True(t, ok, "a different event of the same fingerprint is not a duplicate")
+	assert.Equal(t, 1, suppressed)
+
+	clock.Advance(10 * time.Second)
+	ok, _ = l.allow("order failed", "timeout")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), l.duplicates.Load())
+}
+
+func TestCaptureLimiter_Nil(t *testing.T) {
+	var l *captureLimiter
+	ok, suppressed := l.allow("order failed", "timeout")
+	assert.True(t, ok)
+	assert.Zero(t, suppressed)
+}
+
+func TestCaptureLimiter_PrunesIdleBuckets(t *testing.T) {
+	l, clock := newTestLimiter()
+	for i := range maxCaptureBuckets {
+		l.allow(fmt.Sprint(i), "e")
+	}
+	clock.Advance(time.Hour)
+	l.allow("new", "e")
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestCaptureError_RateLimited(t *testing.T) {
+	events := recordSentryEvents(t)
+	cfg := Config{SentryEnabled: true}
+	WithCaptureLimit()(&cfg)
+	tel := useDefault(t, cfg)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	tel.limiter.now = clock.Now
+
+	for range 5 {
+		CaptureError(context.Background(), errors.New("connection refused"), "loading order failed")
+	}
+	require.Len(t, events.all(), 1)
+
+	clock.Advance(10 * time.Second)
+	CaptureError(context.Background(), errors.New("connection refused"), "loading order failed")
+	require.Len(t, events.all(), 2)
+	assert.Equal(t, 4, events.all()[1].Extra["suppressed_events"])
+	assert.NotContains(t, events.all()[0].Extra, "suppressed_events")
+}
+
+func TestCaptureMetrics(t *testing.T) {
+	cfg := Config{}
+	WithCaptureLimit(CaptureLimitRate(1, time.Minute))(&cfg)
+	tel := newTelemetry(cfg)
+	reader := sdkmetric.NewManualReader()
+	require.NoError(t, tel.registerCaptureMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+
+	tel.CaptureMessage(context.Background(), "cache miss")
+	tel.CaptureMessage(context.Background(), "cache miss")
+	tel.CaptureMessage(context.Background(), "cache cold", CaptureFingerprint("cache miss"))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
+	m := rm.ScopeMetrics[0].Metrics[0]
+	assert.Equal(t, "telemetry.capture.suppressed", m.Name)
+	counts := map[string]int64{}
+	for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+		reason, _ := dp.Attributes.Value("reason")
+		counts[reason.AsString()] = dp.Value
+	}
+	assert.Equal(t, map[string]int64{"duplicate": 1, "rate_limit": 1}, counts)
+}
@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// panicFlushTimeout bounds how long a recovered panic waits for Sentry to send the event.
+const panicFlushTimeout = 2 * time.Second
+
+// PanicError wraps a recovered panic value that is not an error.
+type PanicError struct {
+	Value any
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// panicError returns recovered as an error, wrapping values that are not errors.
+func panicError(recovered any) error {
+	if err, ok := recovered.(error); ok {
+		return err
+	}
+	return &PanicError{Value: recovered}
+}
+
+// Recover reports a panic in progress to Sentry with its stack trace, records it on the
+// current span with error status and flushes Sentry. It must be deferred directly:
+//
+//	defer telemetry.Recover(ctx)
+//
+// The panic is swallowed unless WithRepanic is set. httphelper.HTTPHandler and the
+// natshelper subscribe wrappers already recover for their handlers.
+func Recover(ctx context.Context) {
+	if recovered := recover(); recovered != nil {
+		Default().ReportPanic(ctx, recovered)
+	}
+}
+
+// Recover reports a panic in progress according to the configuration of t. See Recover.
+func (t *Telemetry) Recover(ctx context.Context) {
+	if recovered := recover(); recovered != nil {
+		t.ReportPanic(ctx, recovered)
+	}
+}
+
+// ReportPanic reports a value returned by recover, for code that needs to act on the
+// panic itself, e.g. to answer with a 500. It re-panics with the same value when
+// WithRepanic is set.
+func ReportPanic(ctx context.Context, recovered any) {
+	Default().ReportPanic(ctx, recovered)
+}
+
+// ReportPanic reports recovered according to the configuration of t. See ReportPanic.
+func (t *Telemetry) ReportPanic(ctx context.Context, recovered any) {
+	err := panicError(recovered)
+	stack := debug.Stack()
+
+	if t.cfg.SentryEnabled {
+		hub := HubFromContext(ctx).Clone()
+		hub.Scope().SetLevel(sentry.LevelFatal)
+		hub.CaptureException(err) // Carries the stack trace of the panicking goroutine
+		hub.Flush(panicFlushTimeout)
+	}
+
+	// The span may come from the TracerProvider WithSentry installs, so TraceEnabled is not
+	// the test
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		span.SetAttributes(attribute.Bool("error.panic", true))
+		span.RecordError(err, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	t.Logger().ErrorContext(ctx, "Panic recovered", "panic", recovered, "stack", string(stack))
+
+	if t.cfg.Repanic {
+		panic(recovered)
+	}
+}
+
+// Go runs fn in a new goroutine with its own span named name and its own Sentry hub, and
+// reports a panic in fn like Recover does. The span is a child of the span in ctx; fn
+// receives ctx unchanged otherwise, so pass context.WithoutCancel(ctx) for work that
+// must outlive the caller.
+func Go(ctx context.Context, name string, fn func(ctx context.Context)) {
+	Default().Go(ctx, name, fn)
+}
+
+// Go runs fn according to the configuration of t. See Go.
+func (t *Telemetry) Go(ctx context.Context, name string, fn func(ctx context.Context)) {
+	go func() {
+		ctx, span := t.tracer().Start(ContextWithHub(ctx), name)
+		defer span.End()
+		defer t.Recover(ctx)
+		fn(ctx)
+	}()
+}
@@ -0,0 +1,125 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRecover_ReportsPanic(t *testing.T) {
+	events := recordSentryEvents(t)
+	ctx, spans, span := recordSpans(t)
+	useDefault(t, Config{SentryEnabled: true, TraceEnabled: true})
+
+	func() {
+		defer Recover(ctx)
+		panic("nil map write")
+	}()
+	span.End()
+
+	require.Len(t, events.all(), 1)
+	event := events.all()[0]
+	assert.Equal(t, sentry.LevelFatal, event.Level)
+	require.Len(t, event.Exception, 1)
+	assert.Equal(t, "panic: nil map write", event.Exception[0].Value)
+	require.NotNil(t, event.Exception[0].Stacktrace)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, codes.Error, ended[0].Status().Code)
+	assert.Equal(t, "panic: nil map write", ended[0].Status().Description)
+	require.Len(t, ended[0].Events(), 1)
+	var hasStack bool
+	for _, kv := range ended[0].Events()[0].Attributes {
+		hasStack = hasStack || kv.Key == "exception.stacktrace"
+	}
+	assert.True(t, hasStack)
+}
+
+func TestRecover_MarksSpanWithoutTraceEnabled(t *testing.T) {
+	// WithSentry alone installs a TracerProvider, so the span records
+	ctx, spans, span := recordSpans(t)
+	useDefault(t, Config{SentryEnabled: true})
+
+	func() {
+		defer Recover(ctx)
+		panic("nil map write")
+	}()
+	span.End()
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, codes.Error, ended[0].Status().Code)
+	assert.Contains(t, ended[0].Attributes(), attribute.Bool("error.panic", true))
+}
+
+func TestRecover_ErrorValue(t *testing.T) {
+	events := recordSentryEvents(t)
+	useDefault(t, Config{SentryEnabled: true})
+
+	func() {
+		defer Recover(context.Background())
+		panic(errors.New("index out of range"))
+	}()
+
+	require.Len(t, events.all(), 1)
+	require.Len(t, events.all()[0].Exception, 1)
+	assert.Equal(t, "index out of range", events.all()[0].Exception[0].Value)
+}
+
+func TestRecover_Repanic(t *testing.T) {
+	useDefault(t, Config{Repanic: true})
+
+	assert.PanicsWithValue(t, "boom", func() {
+		defer Recover(context.Background())
+		panic("boom")
+	})
+}
+
+func TestRecover_NoPanic(t *testing.T) {
+	events := recordSentryEvents(t)
+	useDefault(t, Config{SentryEnabled: true})
+
+	func() {
+		defer Recover(context.Background())
+	}()
+
+	assert.Empty(t, events.all())
+}
+
+func TestGo(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := trace.NewTracerProvider(trace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	tel := newTelemetry(Config{TraceEnabled: true})
+	tel.tracerProvider = tp
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	done := make(chan struct{})
+	tel.Go(ctx, "worker", func(_ context.Context) {
+		defer close(done)
+		panic("worker failed")
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("goroutine did not run")
+	}
+	parent.End()
+
+	require.Eventually(t, func() bool { return len(recorder.Ended()) == 2 }, time.Second, time.Millisecond)
+	worker := recorder.Ended()[0]
+	assert.Equal(t, "worker", worker.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), worker.Parent().SpanID())
+	assert.Equal(t, codes.Error, worker.Status().Code)
+}
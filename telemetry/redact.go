@@ -0,0 +1,197 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// defaultMaxPayloadBytes is how much of a message body ends up in breadcrumbs by default.
+	defaultMaxPayloadBytes = 1024
+	// redactedValue replaces the values of denylisted keys.
+	redactedValue = "[REDACTED]"
+)
+
+// defaultRedactedKeys are always masked, in JSON payloads, query strings and capture extras.
+var defaultRedactedKeys = []string{
+	"password", "passwd", "secret", "client_secret",
+	"token", "access_token", "refresh_token", "id_token",
+	"api_key", "apikey", "authorization", "cookie", "session",
+	"credit_card", "card_number", "cvv", "ssn",
+}
+
+// RedactionOption defines a function type for configuring the redaction policy.
+type RedactionOption func(*redactionConfig)
+
+type redactionConfig struct {
+	MaxPayloadBytes int
+	Keys            []string
+}
+
+// WithRedaction configures the policy that the helpers apply to payloads and URLs before
+// they reach Sentry breadcrumbs, span attributes or logs. Without it, payloads are cut at
+// 1024 bytes and a built-in list of keys such as "password", "token" and "authorization"
+// is masked.
+func WithRedaction(opts ...RedactionOption) Option {
+	return func(cfg *Config) {
+		for _, opt := range opts {
+			opt(&cfg.RedactionConfig)
+		}
+	}
+}
+
+// RedactMaxPayloadBytes sets how many bytes of a payload are kept. Longer payloads are cut
+// and marked as truncated; a negative value drops payloads entirely.
+func RedactMaxPayloadBytes(n int) RedactionOption {
+	return func(cfg *redactionConfig) { cfg.MaxPayloadBytes = n }
+}
+
+// RedactKeys adds keys to the denylist. Keys match JSON fields and query parameters
+// case-insensitively, with "-" and "_" treated alike.
+func RedactKeys(keys ...string) RedactionOption {
+	return func(cfg *redactionConfig) { cfg.Keys = append(cfg.Keys, keys...) }
+}
+
+// redactor applies a redactionConfig.
+type redactor struct {
+	maxBytes int
+	keys     map[string]struct{}
+}
+
+func newRedactor(cfg redactionConfig) *redactor {
+	r := &redactor{
+		maxBytes: cfg.MaxPayloadBytes,
+		keys:     map[string]struct{}{},
+	}
+	if r.maxBytes == 0 {
+		r.maxBytes = defaultMaxPayloadBytes
+	}
+	for _, key := range append(defaultRedactedKeys, cfg.Keys...) {
+		r.keys[normalizeKey(key)] = struct{}{}
+	}
+	return r
+}
+
+func normalizeKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "-", "_")
+}
+
+// denied reports whether the value of key must be masked.
+func (r *redactor) denied(key string) bool {
+	_, ok := r.keys[normalizeKey(key)]
+	return ok
+}
+
+// payload masks denylisted fields of a JSON payload and truncates the result. Other
+// payloads are only truncated.
+func (r *redactor) payload(data []byte) string {
+	if r.maxBytes < 0 {
+		return ""
+	}
+	if json.Valid(data) {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err == nil {
+			if masked, err := json.Marshal(r.mask(v)); err == nil {
+				data = masked
+			}
+		}
+	}
+	return r.truncate(string(data))
+}
+
+// mask replaces the values of denylisted keys anywhere in a decoded JSON value.
+func (r *redactor) mask(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if r.denied(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = r.mask(value)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = r.mask(value)
+		}
+	}
+	return v
+}
+
+// truncate cuts s to the maximum payload size without splitting a UTF-8 sequence.
+func (r *redactor) truncate(s string) string {
+	if len(s) <= r.maxBytes {
+		return s
+	}
+	n := r.maxBytes
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return fmt.Sprintf("%s...[truncated %d bytes]", s[:n], len(s)-n)
+}
+
+// url masks the password and denylisted query parameters of u.
+func (r *redactor) url(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	scrubbed := *u
+	if scrubbed.RawQuery != "" {
+		params := strings.Split(scrubbed.RawQuery, "&")
+		for i, param := range params {
+			key, _, hasValue := strings.Cut(param, "=")
+			name, err := url.QueryUnescape(key)
+			if err != nil {
+				name = key
+			}
+			if hasValue && r.denied(name) {
+				params[i] = key + "=" + redactedValue
+			}
+		}
+		scrubbed.RawQuery = strings.Join(params, "&")
+	}
+	return scrubbed.Redacted()
+}
+
+// value masks v when key is denylisted and truncates string values.
+func (r *redactor) value(key string, v any) any {
+	if r.denied(key) {
+		return redactedValue
+	}
+	if s, ok := v.(string); ok {
+		return r.truncate(s)
+	}
+	return v
+}
+
+// RedactPayload applies the redaction policy of the default instance to a message body.
+// JSON payloads have denylisted fields masked at any depth; all payloads are truncated to
+// the configured size. The helpers in httphelper and natshelper call it before adding
+// payloads to breadcrumbs.
+func RedactPayload(data []byte) string {
+	return Default().RedactPayload(data)
+}
+
+// RedactPayload applies the redaction policy of t to data. See RedactPayload.
+func (t *Telemetry) RedactPayload(data []byte) string {
+	return t.redact.payload(data)
+}
+
+// RedactURL formats u with its password and denylisted query parameters masked, using the
+// policy of the default instance:
+//
+//	https://api.example.com/orders?id=42&token=[REDACTED]
+func RedactURL(u *url.URL) string {
+	return Default().RedactURL(u)
+}
+
+// RedactURL formats u with the policy of t. See RedactURL.
+func (t *Telemetry) RedactURL(u *url.URL) string {
+	return t.redact.url(u)
+}
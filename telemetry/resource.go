@@ -0,0 +1,96 @@
+package telemetry
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+
+	"github.com/TMSLabs/go-tooling/k8shelper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+)
+
+// vcsRevisionKey carries the commit the binary was built from, as recorded by the Go toolchain.
+const vcsRevisionKey = attribute.Key("vcs.revision")
+
+// newResource builds the OpenTelemetry resource shared by all providers.
+// ResourceAttributes win over detected attributes, and the service attributes win over both.
+func newResource(cfg *Config) *resource.Resource {
+	detected := detectedAttributes()
+	attrs := make([]attribute.KeyValue, 0, len(detected)+len(cfg.ResourceAttributes)+3)
+	attrs = append(attrs, detected...)
+	for k, v := range cfg.ResourceAttributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	attrs = append(attrs,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.DeploymentEnvironment(cfg.Environment),
+	)
+	if cfg.SentryConfig.Release != "" {
+		attrs = append(attrs, semconv.ServiceVersion(cfg.SentryConfig.Release))
+	}
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+}
+
+// detectedAttributes describes the Kubernetes pod, container, host, process and Go runtime
+// the service runs in, plus the VCS revision it was built from. They do not change while
+// the process runs, so detection happens once.
+var detectedAttributes = sync.OnceValue(func() []attribute.KeyValue {
+	// Detectors that fail leave their attributes out; the rest are still returned
+	res, _ := resource.New(context.Background(),
+		resource.WithHost(),
+		resource.WithContainerID(),
+		resource.WithProcessPID(),
+		resource.WithProcessExecutableName(),
+		resource.WithProcessRuntimeName(),
+		resource.WithProcessRuntimeVersion(),
+		resource.WithProcessRuntimeDescription(),
+	)
+	var attrs []attribute.KeyValue
+	if res != nil {
+		attrs = append(attrs, res.Attributes()...)
+	}
+	attrs = append(attrs, kubernetesAttributes()...)
+	return append(attrs, buildInfoAttributes()...)
+})
+
+// kubernetesAttributes returns the namespace, pod and node of the service.
+// Outside Kubernetes it returns nothing.
+func kubernetesAttributes() []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if namespace := k8shelper.GetNamespace(); namespace != "" {
+		attrs = append(attrs, semconv.K8SNamespaceName(namespace))
+	}
+	if pod := k8shelper.GetPodName(); pod != "" {
+		attrs = append(attrs, semconv.K8SPodName(pod))
+	}
+	if node := k8shelper.GetNodeName(); node != "" {
+		attrs = append(attrs, semconv.K8SNodeName(node))
+	}
+	return attrs
+}
+
+// buildInfoAttributes returns the VCS revision stamped into the binary by go build.
+func buildInfoAttributes() []attribute.KeyValue {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && setting.Value != "" {
+			return []attribute.KeyValue{vcsRevisionKey.String(setting.Value)}
+		}
+	}
+	return nil
+}
+
+// resourceTags returns the detected attributes as Sentry tags, so events can be filtered
+// by the same pod, node or revision as traces and metrics.
+func resourceTags() map[string]string {
+	tags := map[string]string{}
+	for _, kv := range detectedAttributes() {
+		tags[string(kv.Key)] = kv.Value.Emit()
+	}
+	return tags
+}
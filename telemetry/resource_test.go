@@ -0,0 +1,66 @@
+package telemetry
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewResource_DetectedAttributes(t *testing.T) {
+	cfg := &Config{ServiceName: "resource-test", Environment: "staging"}
+
+	attrs := map[string]string{}
+	for _, kv := range newResource(cfg).Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+
+	assert.NotEmpty(t, attrs["host.name"])
+	assert.NotEmpty(t, attrs["process.pid"])
+	assert.Equal(t, "go", attrs["process.runtime.name"])
+	assert.Equal(t, runtime.Version(), attrs["process.runtime.version"])
+}
+
+func TestNewResource_ResourceAttributesOverrideDetected(t *testing.T) {
+	cfg := &Config{ServiceName: "resource-test", Environment: "staging"}
+	WithResourceAttributes(map[string]string{"host.name": "explicit-host"})(cfg)
+
+	attrs := map[string]string{}
+	for _, kv := range newResource(cfg).Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+
+	assert.Equal(t, "explicit-host", attrs["host.name"])
+}
+
+func TestKubernetesAttributes_DownwardAPI(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", "payments-prod")
+	t.Setenv("POD_NAME", "orders-7d9f8-abcde")
+	t.Setenv("NODE_NAME", "node-1")
+
+	attrs := map[string]string{}
+	for _, kv := range kubernetesAttributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+
+	assert.Equal(t, map[string]string{
+		"k8s.namespace.name": "payments-prod",
+		"k8s.pod.name":       "orders-7d9f8-abcde",
+		"k8s.node.name":      "node-1",
+	}, attrs)
+}
+
+func TestKubernetesAttributes_OutsideKubernetes(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", "")
+	t.Setenv("POD_NAME", "")
+	t.Setenv("NODE_NAME", "")
+
+	assert.Empty(t, kubernetesAttributes())
+}
+
+func TestResourceTags(t *testing.T) {
+	tags := resourceTags()
+
+	assert.Equal(t, "go", tags["process.runtime.name"])
+	assert.NotEmpty(t, tags["host.name"])
+}
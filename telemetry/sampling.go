@@ -0,0 +1,69 @@
+package telemetry
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// sampleRule samples spans whose name starts with pattern using its own sampler.
+type sampleRule struct {
+	pattern string
+	re      *regexp.Regexp
+	sampler sdktrace.Sampler
+}
+
+// newSampleRule compiles pattern into a prefix match where "*" matches any sequence of characters.
+func newSampleRule(pattern string, ratio float64) sampleRule {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return sampleRule{
+		pattern: pattern,
+		re:      regexp.MustCompile("^" + strings.Join(parts, ".*")),
+		sampler: sdktrace.TraceIDRatioBased(ratio),
+	}
+}
+
+// ruleSampler applies the first rule matching the span name and falls back to base.
+type ruleSampler struct {
+	rules []sampleRule
+	base  sdktrace.Sampler
+}
+
+func (s ruleSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, rule := range s.rules {
+		if rule.re.MatchString(p.Name) {
+			return rule.sampler.ShouldSample(p)
+		}
+	}
+	return s.base.ShouldSample(p)
+}
+
+func (s ruleSampler) Description() string {
+	rules := make([]string, len(s.rules))
+	for i, rule := range s.rules {
+		rules[i] = fmt.Sprintf("%q:%s", rule.pattern, rule.sampler.Description())
+	}
+	return fmt.Sprintf("RuleBased{rules:[%s],base:%s}", strings.Join(rules, ","), s.base.Description())
+}
+
+// newSampler builds the sampler described by cfg. Rules are checked first, then the
+// configured sampler (AlwaysSample by default). With parent-based sampling, the result
+// only applies to root spans and child spans follow their parent's decision.
+func newSampler(cfg traceConfig) sdktrace.Sampler {
+	sampler := cfg.Sampler
+	if sampler == nil {
+		sampler = sdktrace.AlwaysSample()
+	}
+	if len(cfg.SampleRules) > 0 {
+		sampler = ruleSampler{rules: cfg.SampleRules, base: sampler}
+	}
+	if cfg.ParentBased {
+		sampler = sdktrace.ParentBased(sampler)
+	}
+	return sampler
+}
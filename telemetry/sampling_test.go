@@ -0,0 +1,116 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordedSpanNames starts each span name as a root span and returns the names that were sampled.
+func recordedSpanNames(cfg traceConfig, names ...string) []string {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(newSampler(cfg)),
+		sdktrace.WithSpanProcessor(recorder),
+	)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	tracer := tp.Tracer("sampling-test")
+	for _, name := range names {
+		_, span := tracer.Start(context.Background(), name)
+		span.End()
+	}
+
+	var recorded []string
+	for _, span := range recorder.Ended() {
+		recorded = append(recorded, span.Name())
+	}
+	return recorded
+}
+
+func TestNewSampler_DefaultAlwaysSamples(t *testing.T) {
+	recorded := recordedSpanNames(traceConfig{}, "GET /orders", "GET /healthz")
+
+	assert.Equal(t, []string{"GET /orders", "GET /healthz"}, recorded)
+}
+
+func TestNewSampler_Ratio(t *testing.T) {
+	cfg := traceConfig{}
+	TraceSampleRatio(0)(&cfg)
+
+	assert.Empty(t, recordedSpanNames(cfg, "GET /orders", "nats.receive.orders"))
+}
+
+func TestNewSampler_RulesDropHealthTraffic(t *testing.T) {
+	cfg := traceConfig{}
+	TraceSampleRule("GET /healthz", 0)(&cfg)
+	TraceSampleRule("nats.receive.*healthz", 0)(&cfg)
+
+	recorded := recordedSpanNames(cfg,
+		"GET /healthz",
+		"GET /healthz/ready",
+		"nats.receive.orders-service.healthz",
+		"nats.receive.orders",
+		"POST /orders",
+	)
+
+	assert.Equal(t, []string{"nats.receive.orders", "POST /orders"}, recorded)
+}
+
+func TestNewSampler_FirstMatchingRuleWins(t *testing.T) {
+	cfg := traceConfig{}
+	TraceSampleRule("nats.receive.orders", 1)(&cfg)
+	TraceSampleRule("nats.receive.*", 0)(&cfg)
+
+	recorded := recordedSpanNames(cfg, "nats.receive.orders", "nats.receive.invoices")
+
+	assert.Equal(t, []string{"nats.receive.orders"}, recorded)
+}
+
+func TestNewSampler_ParentBased(t *testing.T) {
+	cfg := traceConfig{}
+	TraceSampleRatio(0)(&cfg)
+	TraceParentBased()(&cfg)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(newSampler(cfg)),
+		sdktrace.WithSpanProcessor(recorder),
+	)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	// A remote parent that was sampled upstream
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01},
+		SpanID:     trace.SpanID{0x01},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), parent)
+
+	_, child := tp.Tracer("test").Start(ctx, "child")
+	child.End()
+	_, root := tp.Tracer("test").Start(context.Background(), "root")
+	root.End()
+
+	ended := recorder.Ended()
+	if assert.Len(t, ended, 1) {
+		assert.Equal(t, "child", ended[0].Name())
+	}
+}
+
+func TestNewSampler_Description(t *testing.T) {
+	cfg := traceConfig{}
+	TraceSampleRule("GET /healthz", 0)(&cfg)
+	TraceParentBased()(&cfg)
+
+	description := newSampler(cfg).Description()
+
+	assert.Contains(t, description, "ParentBased")
+	assert.Contains(t, description, `"GET /healthz"`)
+	assert.Contains(t, description, "AlwaysOnSampler")
+}
@@ -0,0 +1,91 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanOption defines a function type for configuring the spans started by Trace and TraceValue.
+type SpanOption func(*spanConfig)
+
+type spanConfig struct {
+	attrs   []attribute.KeyValue
+	kind    trace.SpanKind
+	capture []CaptureOption
+}
+
+// SpanAttributes sets attributes on the span when it starts.
+func SpanAttributes(attrs ...attribute.KeyValue) SpanOption {
+	return func(cfg *spanConfig) { cfg.attrs = append(cfg.attrs, attrs...) }
+}
+
+// SpanKind sets the kind of the span. The default is trace.SpanKindInternal.
+func SpanKind(kind trace.SpanKind) SpanOption {
+	return func(cfg *spanConfig) { cfg.kind = kind }
+}
+
+// SpanCapture passes opts to CaptureError when the traced function fails.
+func SpanCapture(opts ...CaptureOption) SpanOption {
+	return func(cfg *spanConfig) { cfg.capture = append(cfg.capture, opts...) }
+}
+
+// Trace runs fn in a new span named name, a child of the span in ctx. An error returned
+// by fn is captured with CaptureError, so error classes, rate limits and Sentry apply, and
+// fails the span unless its class says otherwise. A panic in fn is reported like Recover
+// does and returned as an error, unless WithRepanic is set. The span comes from the
+// provider installed by Init. Example usage:
+//
+//	err := telemetry.Trace(ctx, "orders.reconcile", func(ctx context.Context) error {
+//	    return reconcile(ctx, batch)
+//	}, telemetry.SpanAttributes(attribute.Int("batch.size", len(batch))))
+func Trace(ctx context.Context, name string, fn func(ctx context.Context) error, opts ...SpanOption) error {
+	return Default().Trace(ctx, name, fn, opts...)
+}
+
+// Trace runs fn in a span from t's provider. See Trace.
+func (t *Telemetry) Trace(ctx context.Context, name string, fn func(ctx context.Context) error, opts ...SpanOption) error {
+	_, err := traceValue(ctx, t, name, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}, opts)
+	return err
+}
+
+// TraceValue is Trace for functions that return a value:
+//
+//	order, err := telemetry.TraceValue(ctx, "orders.load", func(ctx context.Context) (*Order, error) {
+//	    return repo.Load(ctx, id)
+//	})
+//
+// It uses the default instance.
+func TraceValue[T any](ctx context.Context, name string, fn func(ctx context.Context) (T, error), opts ...SpanOption) (T, error) {
+	return traceValue(ctx, Default(), name, fn, opts)
+}
+
+// traceValue implements Trace and TraceValue for t. Methods can't have type parameters.
+func traceValue[T any](ctx context.Context, t *Telemetry, name string, fn func(ctx context.Context) (T, error), opts []SpanOption) (value T, err error) {
+	cfg := spanConfig{kind: trace.SpanKindInternal}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ctx, span := t.tracer().Start(ctx, name, trace.WithAttributes(cfg.attrs...), trace.WithSpanKind(cfg.kind))
+	defer span.End()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = panicError(recovered)
+			t.ReportPanic(ctx, recovered)
+		}
+	}()
+
+	value, err = fn(ctx)
+	if err != nil {
+		t.CaptureError(ctx, err, name+" failed", cfg.capture...)
+		if !t.cfg.TraceEnabled {
+			// CaptureError only records on spans with tracing enabled; this span is ours
+			t.RecordError(ctx, err)
+		}
+	}
+	return value, err
+}
@@ -0,0 +1,128 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// useTracedDefault installs a default instance whose tracer provider records spans.
+func useTracedDefault(t *testing.T, cfg Config) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tp := trace.NewTracerProvider(trace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	tel := useDefault(t, cfg)
+	tel.tracerProvider = tp
+	return recorder
+}
+
+func TestTrace_Success(t *testing.T) {
+	spans := useTracedDefault(t, Config{TraceEnabled: true})
+
+	var inner oteltrace.SpanContext
+	err := Trace(context.Background(), "orders.reconcile", func(ctx context.Context) error {
+		inner = oteltrace.SpanContextFromContext(ctx)
+		return nil
+	}, SpanAttributes(attribute.Int("batch.size", 3)), SpanKind(oteltrace.SpanKindConsumer))
+	require.NoError(t, err)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, "orders.reconcile", ended[0].Name())
+	assert.Equal(t, inner.SpanID(), ended[0].SpanContext().SpanID(), "fn runs inside the span")
+	assert.Equal(t, oteltrace.SpanKindConsumer, ended[0].SpanKind())
+	assert.Contains(t, ended[0].Attributes(), attribute.Int("batch.size", 3))
+	assert.Equal(t, codes.Unset, ended[0].Status().Code)
+}
+
+func TestTrace_Error(t *testing.T) {
+	events := recordSentryEvents(t)
+	spans := useTracedDefault(t, Config{SentryEnabled: true, TraceEnabled: true})
+
+	err := Trace(context.Background(), "orders.reconcile", func(_ context.Context) error {
+		return errors.New("ledger unavailable")
+	}, SpanCapture(CaptureTag("tenant", "acme")))
+	require.EqualError(t, err, "ledger unavailable")
+
+	require.Len(t, events.all(), 1)
+	assert.Equal(t, "acme", events.all()[0].Tags["tenant"])
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, codes.Error, ended[0].Status().Code)
+	assert.Len(t, ended[0].Events(), 1)
+}
+
+func TestTrace_ErrorWithoutTraceEnabled(t *testing.T) {
+	spans := useTracedDefault(t, Config{})
+
+	err := Trace(context.Background(), "orders.reconcile", func(_ context.Context) error {
+		return errors.New("ledger unavailable")
+	})
+	require.Error(t, err)
+
+	require.Len(t, spans.Ended(), 1)
+	assert.Equal(t, codes.Error, spans.Ended()[0].Status().Code)
+}
+
+func TestTrace_ClassifiedError(t *testing.T) {
+	events := recordSentryEvents(t)
+	spans := useTracedDefault(t, Config{SentryEnabled: true, TraceEnabled: true})
+
+	err := Trace(context.Background(), "orders.reconcile", func(_ context.Context) error {
+		return context.Canceled
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	assert.Empty(t, events.all())
+	require.Len(t, spans.Ended(), 1)
+	assert.Equal(t, codes.Unset, spans.Ended()[0].Status().Code)
+}
+
+func TestTrace_Panic(t *testing.T) {
+	spans := useTracedDefault(t, Config{})
+
+	err := Trace(context.Background(), "orders.reconcile", func(_ context.Context) error {
+		panic("nil order")
+	})
+	var panicErr *PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "nil order", panicErr.Value)
+
+	require.Len(t, spans.Ended(), 1)
+	assert.Equal(t, codes.Error, spans.Ended()[0].Status().Code)
+}
+
+func TestTraceValue(t *testing.T) {
+	spans := useTracedDefault(t, Config{})
+
+	total, err := TraceValue(context.Background(), "orders.total", func(_ context.Context) (int, error) {
+		return 42, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 42, total)
+	require.Len(t, spans.Ended(), 1)
+	assert.Equal(t, "orders.total", spans.Ended()[0].Name())
+}
+
+func TestTrace_GlobalProvider(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := trace.NewTracerProvider(trace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	useDefault(t, Config{})
+
+	require.NoError(t, Trace(context.Background(), "global", func(_ context.Context) error { return nil }))
+	require.Len(t, recorder.Ended(), 1)
+}
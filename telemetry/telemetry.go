@@ -3,30 +3,34 @@ package telemetry
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/getsentry/sentry-go"
-	sentryotel "github.com/getsentry/sentry-go/otel"
 	"github.com/nats-io/nats.go"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/log/global"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
 	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
 	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 
 	"log/slog"
 )
 
-// TelemetryConfig is the configuration for telemetry.
-var TelemetryConfig = config{}
-
 // --- slog helpers ---
 type otelHandler struct {
 	slog.Handler
+	identity bool // Add the identity ids from the baggage, see WithIdentityFromBaggage
 }
 
 func newOTelHandler(base slog.Handler) *otelHandler {
@@ -41,60 +45,272 @@ func (h *otelHandler) Handle(ctx context.Context, r slog.Record) error {
 			slog.String("span_id", spanCtx.SpanID().String()),
 		)
 	}
+	if h.identity {
+		r.AddAttrs(identityAttrs(ctx)...)
+	}
 	return h.Handler.Handle(ctx, r)
 }
 
+func (h *otelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &otelHandler{Handler: h.Handler.WithAttrs(attrs), identity: h.identity}
+}
+
+func (h *otelHandler) WithGroup(name string) slog.Handler {
+	return &otelHandler{Handler: h.Handler.WithGroup(name), identity: h.identity}
+}
+
+// newBaseHandler builds the text or JSON handler described by cfg, or filters the handler
+// set with SlogHandler by level.
+func newBaseHandler(cfg slogConfig, level slog.Leveler) slog.Handler {
+	if cfg.handler != nil {
+		return newFanoutHandler(level, cfg.handler)
+	}
+	output := cfg.output
+	if output == nil {
+		output = os.Stdout
+	}
+	handlerOpts := &slog.HandlerOptions{
+		Level:       level,
+		AddSource:   cfg.addSource,
+		ReplaceAttr: cfg.replaceAttr,
+	}
+	if cfg.format == LogFormatJSON {
+		return slog.NewJSONHandler(output, handlerOpts)
+	}
+	return slog.NewTextHandler(output, handlerOpts)
+}
+
 // --- end ---
 
-// initTelemetry initializes slog, OpenTelemetry, and Sentry.
-// Returns the TracerProvider for shutdown.
-func initTelemetry(
-	serviceName string,
-	environment string,
-	opts ...Option,
-) (*sdktrace.TracerProvider, error) {
+// providers holds the OpenTelemetry SDK providers created by New.
+type providers struct {
+	tracerProvider *sdktrace.TracerProvider
+	meterProvider  *sdkmetric.MeterProvider
+	loggerProvider *sdklog.LoggerProvider
+}
+
+// Telemetry owns everything New sets up: the merged configuration, the logger and its
+// runtime level, the OpenTelemetry providers, the NATS connection and the health state.
+// Several instances can live in one process, e.g. in tests. SetDefault makes one of them
+// the target of the package-level functions and installs its logger and providers globally.
+//
+// Sentry has a single process-wide client, which New initializes when WithSentry is set.
+type Telemetry struct {
+	cfg          Config
+	logger       *slog.Logger
+	level        *levelState
+	health       *healthState
+	checks       *checkRegistry
+	redact       *redactor
+	errorClasses *errorClassRegistry
+	limiter      *captureLimiter // nil unless WithCaptureLimit is set
 
-	cfg := &config{}
-	for _, opt := range opts {
-		opt(cfg)
+	providers
+	propagator     propagation.TextMapPropagator
+	metricsHandler http.Handler
+	nc             *nats.Conn
+	natsClosed     chan struct{} // Closed once nc has finished draining; nil if the caller owns nc
+	stopHealth     context.CancelFunc
+	healthDone     chan struct{} // Closed when the health check loop has returned
+	sentryStarted  bool          // sentry.Init succeeded, so Shutdown flushes Sentry
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+var (
+	// defaultTelemetry is the instance installed by SetDefault.
+	defaultTelemetry atomic.Pointer[Telemetry]
+	// fallbackTelemetry serves the package-level functions until SetDefault is called.
+	fallbackTelemetry = newTelemetry(Config{})
+)
+
+func newTelemetry(cfg Config) *Telemetry {
+	t := &Telemetry{
+		cfg:          cfg,
+		level:        &levelState{},
+		health:       newHealthState(),
+		checks:       &checkRegistry{cacheTTL: cfg.HealthCheckCacheTTL},
+		redact:       newRedactor(cfg.RedactionConfig),
+		errorClasses: newErrorClassRegistry(),
+	}
+	if cfg.CaptureLimitEnabled {
+		t.limiter = newCaptureLimiter(cfg.CaptureLimitConfig)
+	}
+	t.registerBuiltinChecks()
+	return t
+}
+
+// New initializes slog, NATS, Sentry and OpenTelemetry as configured by opts and returns
+// the instance that owns them. Unlike Init, it does not replace the global slog logger or
+// OpenTelemetry providers; call SetDefault for that. If a component fails to start, the
+// ones started before it are shut down again and the error is returned.
+func New(serviceName string, environment string, opts ...Option) (*Telemetry, error) {
+	cfg, err := newConfig(serviceName, environment, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	t := newTelemetry(*cfg)
+	if err := t.start(); err != nil {
+		return nil, err
 	}
-	cfg.ServiceName = serviceName
-	cfg.Environment = environment
-	TelemetryConfig = *cfg
+	return t, nil
+}
+
+// SetDefault makes t the instance behind the package-level functions such as
+// HealthzEndpointHandler, LogLevelEndpointHandler and CaptureError. It also installs
+// t's logger, providers and propagator as the slog and OpenTelemetry globals.
+func SetDefault(t *Telemetry) {
+	slog.SetDefault(t.Logger())
+	if t.tracerProvider != nil {
+		otel.SetTracerProvider(t.tracerProvider)
+		otel.SetTextMapPropagator(t.propagator)
+	}
+	if t.meterProvider != nil {
+		otel.SetMeterProvider(t.meterProvider)
+	}
+	if t.loggerProvider != nil {
+		global.SetLoggerProvider(t.loggerProvider)
+	}
+	defaultTelemetry.Store(t)
+}
+
+// Default returns the instance installed by SetDefault or Init. Before that, it returns
+// an instance with nothing enabled that logs through slog.Default.
+func Default() *Telemetry {
+	if t := defaultTelemetry.Load(); t != nil {
+		return t
+	}
+	return fallbackTelemetry
+}
+
+// Config returns a copy of the configuration merged from the options and, with FromEnv,
+// the environment.
+func (t *Telemetry) Config() Config {
+	return t.cfg
+}
+
+// Logger returns the logger built from the WithSlog options.
+func (t *Telemetry) Logger() *slog.Logger {
+	if t.logger == nil {
+		return slog.Default()
+	}
+	return t.logger
+}
+
+// TracerProvider returns the provider that carries the Sentry span processor and the
+// OTLP exporter, or a no-op provider when neither is enabled.
+func (t *Telemetry) TracerProvider() trace.TracerProvider {
+	if t.tracerProvider == nil {
+		return noop.NewTracerProvider()
+	}
+	return t.tracerProvider
+}
+
+// instrumentationName is the scope of the spans and metrics the telemetry package creates itself.
+const instrumentationName = "github.com/TMSLabs/go-tooling/telemetry"
+
+// tracer returns a tracer from t's provider, or from the global provider when t has none,
+// so helpers keep working with a provider installed outside New.
+func (t *Telemetry) tracer() trace.Tracer {
+	if t.tracerProvider == nil {
+		return otel.Tracer(instrumentationName)
+	}
+	return t.tracerProvider.Tracer(instrumentationName)
+}
+
+// MeterProvider returns the provider configured by WithMetrics, or a no-op provider.
+func (t *Telemetry) MeterProvider() metric.MeterProvider {
+	if t.meterProvider == nil {
+		return metricnoop.NewMeterProvider()
+	}
+	return t.meterProvider
+}
+
+// NATSConn returns the connection used for WithNATS, or nil.
+func (t *Telemetry) NATSConn() *nats.Conn {
+	return t.nc
+}
+
+// startUnwindTimeout bounds the shutdown of the parts started before a failing step.
+const startUnwindTimeout = 5 * time.Second
+
+// start initializes slog, NATS, Sentry and OpenTelemetry in that order. If a step fails,
+// the steps that already succeeded are shut down again, since the caller gets no instance.
+func (t *Telemetry) start() (err error) {
+	defer func() {
+		if err != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), startUnwindTimeout)
+			defer cancel()
+			_ = t.shutdown(ctx)
+		}
+	}()
+
+	cfg := &t.cfg
+	serviceName := cfg.ServiceName
 
 	// --- slog init ---
 	logLevel := slog.LevelInfo
 	if cfg.SlogConfig.logLevel != slog.LevelInfo {
 		logLevel = cfg.SlogConfig.logLevel
 	}
-	baseHandler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})
-	otelHandler := newOTelHandler(baseHandler)
-	logger := slog.New(otelHandler)
-	slog.SetDefault(logger)
-	slog.Info("slog initialized", "level", logLevel)
+	t.level.reset(logLevel)
+	baseHandler := newBaseHandler(cfg.SlogConfig, &t.level.level)
+	if cfg.LogExportEnabled {
+		lp, err := initLogExport(context.Background(), cfg, newResource(cfg))
+		if err != nil {
+			slog.Error("OpenTelemetry log export init failed", "err", err)
+			return err
+		}
+		t.loggerProvider = lp
+		baseHandler = newFanoutHandler(&t.level.level, baseHandler, newLogExportHandler(serviceName, lp))
+	}
+	handler := newOTelHandler(baseHandler)
+	handler.identity = cfg.IdentityFromBaggage
+	t.logger = slog.New(handler)
+	logger := t.logger
+	logger.Info("slog initialized", "level", logLevel)
 
 	// --- NATS init ---
 	if cfg.NatsEnabled {
-		if cfg.NatsConfig.URL == "" {
-			slog.Error("NATS URL is required but not set")
-			return nil, fmt.Errorf("nats URL is required but not set")
-		}
-		nc, err := nats.Connect(cfg.NatsConfig.URL, nats.Name(serviceName))
-		if err != nil {
-			slog.Error("NATS connection failed", "err", err)
-			return nil, fmt.Errorf("nats connection failed: %w", err)
+		nc := cfg.NatsConfig.Conn
+		if nc == nil {
+			if cfg.NatsConfig.URL == "" {
+				logger.Error("NATS URL is required but not set")
+				return fmt.Errorf("nats URL is required but not set")
+			}
+			natsClosed := make(chan struct{})
+			var err error
+			nc, err = nats.Connect(cfg.NatsConfig.URL,
+				nats.Name(serviceName),
+				nats.ClosedHandler(func(_ *nats.Conn) { close(natsClosed) }),
+			)
+			if err != nil {
+				logger.Error("NATS connection failed", "err", err)
+				return fmt.Errorf("nats connection failed: %w", err)
+			}
+			t.natsClosed = natsClosed
+			logger.Info("NATS initialized", "url", cfg.NatsConfig.URL)
 		}
-		slog.Info("NATS initialized", "url", cfg.NatsConfig.URL)
+		t.nc = nc
+		t.registerNATSCheck(nc)
 
 		// Subscribe to health check Environment
-		go HealthzEventChecker(nc, serviceName)
+		healthCtx, stopHealth := context.WithCancel(context.Background())
+		t.stopHealth = stopHealth
+		t.healthDone = make(chan struct{})
+		go func() {
+			defer close(t.healthDone)
+			t.runHealthzEvents(healthCtx, nc, serviceName)
+		}()
 	}
 
 	// --- Sentry init ---
 	if cfg.SentryEnabled {
 		if cfg.SentryConfig.DSN == "" {
-			slog.Error("Sentry DSN is required but not set")
-			return nil, fmt.Errorf("sentry DSN is required but not set")
+			logger.Error("Sentry DSN is required but not set")
+			return fmt.Errorf("sentry DSN is required but not set")
 		}
 
 		sentryConfig := sentry.ClientOptions{
@@ -109,7 +325,7 @@ func initTelemetry(
 			}),
 		}
 
-		sentryConfig.Environment = environment
+		sentryConfig.Environment = cfg.Environment
 		if cfg.SentryConfig.Environment != "" {
 			sentryConfig.Environment = cfg.SentryConfig.Environment
 		}
@@ -119,90 +335,174 @@ func initTelemetry(
 		if cfg.SentryConfig.DSN != "" {
 			sentryConfig.Dsn = cfg.SentryConfig.DSN
 		}
+		for _, fn := range cfg.SentryConfig.ClientOptions {
+			fn(&sentryConfig)
+		}
 
 		if err := sentry.Init(sentryConfig); err != nil {
-			slog.Error("Sentry initialization failed", "err", err)
-			return nil, err
+			logger.Error("Sentry initialization failed", "err", err)
+			return err
 		}
+		sentry.ConfigureScope(func(scope *sentry.Scope) {
+			scope.SetTags(resourceTags())
+		})
 
-		tp := sdktrace.NewTracerProvider(
-			sdktrace.WithSpanProcessor(sentryotel.NewSentrySpanProcessor()),
-		)
-		otel.SetTracerProvider(tp)
-		otel.SetTextMapPropagator(sentryotel.NewSentryPropagator())
-
-		slog.Info("Sentry initialized")
+		t.sentryStarted = true
+		logger.Info("Sentry initialized")
 	}
 
 	// --- OpenTelemetry init ---
 	if cfg.TraceEnabled {
 		// check if OTEL_EXPORTER_ENDPOINT is set
-		if cfg.TraceConfig.ExporterURL == "" {
-			slog.Error("OpenTelemetry Exporter URL is required but not set")
-			return nil, fmt.Errorf("OpenTelemetry Exporter URL is required but not set")
+		if cfg.TraceConfig.ExporterURL == "" && len(cfg.TraceConfig.Processors) == 0 {
+			logger.Error("OpenTelemetry Exporter URL is required but not set")
+			return fmt.Errorf("OpenTelemetry Exporter URL is required but not set")
 		}
+	}
 
-		ctx := context.Background()
-		exporter, err := otlptracegrpc.New(ctx,
-			otlptracegrpc.WithInsecure(),
-			otlptracegrpc.WithEndpoint(cfg.TraceConfig.ExporterURL),
-		)
+	// One TracerProvider carries the Sentry span processor and the OTLP batcher,
+	// so neither backend loses spans when both are enabled.
+	if cfg.SentryEnabled || cfg.TraceEnabled {
+		tpOpts := []sdktrace.TracerProviderOption{
+			sdktrace.WithSampler(newSampler(cfg.TraceConfig)),
+			sdktrace.WithResource(newResource(cfg)),
+		}
+		if cfg.SentryEnabled {
+			tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(sentrySpanProcessor()))
+		}
+		if cfg.TraceEnabled && cfg.TraceConfig.ExporterURL != "" {
+			exporter, err := newTraceExporter(context.Background(), cfg.TraceConfig)
+			if err != nil {
+				logger.Error("otel exporter init failed", "err", err)
+				return err
+			}
+			tpOpts = append(tpOpts, sdktrace.WithBatcher(exporter))
+		}
+		for _, sp := range cfg.TraceConfig.Processors {
+			tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(sp))
+		}
+
+		t.tracerProvider = sdktrace.NewTracerProvider(tpOpts...)
+		t.propagator = newPropagator(cfg.SentryEnabled)
+		logger.Info("OpenTelemetry initialized", "sentry", cfg.SentryEnabled, "otlp", cfg.TraceEnabled)
+	}
+
+	// --- OpenTelemetry metrics init ---
+	if cfg.MetricsEnabled {
+		mp, err := t.initMetrics(context.Background(), newResource(cfg))
 		if err != nil {
-			slog.Error("otel exporter init failed", "err", err)
-			return nil, err
-		}
-
-		tp := sdktrace.NewTracerProvider(
-			sdktrace.WithSampler(sdktrace.AlwaysSample()),
-			sdktrace.WithBatcher(exporter),
-			sdktrace.WithResource(
-				resource.NewWithAttributes(
-					semconv.SchemaURL,
-					semconv.ServiceName(serviceName),
-					semconv.DeploymentEnvironment(cfg.Environment),
-					semconv.ServiceVersion(cfg.SentryConfig.Release),
-				),
-			),
-		)
-		otel.SetTracerProvider(tp)
-		otel.SetTextMapPropagator(
-			propagation.NewCompositeTextMapPropagator(
-				propagation.TraceContext{},
-				propagation.Baggage{},
-			),
-		)
-		slog.Info("OpenTelemetry initialized")
-		return tp, nil
+			return err
+		}
+		t.meterProvider = mp
+		if err := t.registerCaptureMetrics(mp); err != nil {
+			logger.Error("capture metrics init failed", "err", err)
+			return err
+		}
 	}
 
-	return nil, nil
+	return nil
 }
 
-// ShutdownFunc is a function type for cleaning up telemetry resources
-type ShutdownFunc func()
+// Shutdown marks t as draining, so the readiness probe fails, cancels a pending log level
+// revert and stops everything New started in reverse order: it drains the NATS
+// connection, stops the health check loop, flushes and stops the OpenTelemetry providers
+// and finally flushes Sentry. ctx bounds the whole sequence. Every step runs even if an
+// earlier one fails; the errors are joined. Calls after the first return the first result.
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	t.shutdownOnce.Do(func() {
+		t.shutdownErr = t.shutdown(ctx)
+	})
+	return t.shutdownErr
+}
 
-// Init initializes all telemetry and returns a shutdown function to defer in main.
-func Init(serviceName string, environment string, opts ...Option) (ShutdownFunc, error) {
-	cfg := &config{}
-	for _, opt := range opts {
-		opt(cfg)
+func (t *Telemetry) shutdown(ctx context.Context) error {
+	logger := t.Logger()
+	var errs []error
+
+	t.StartDraining()
+	t.level.cancelRevert()
+
+	if t.natsClosed != nil {
+		if err := t.drainNATS(ctx); err != nil {
+			logger.Error("Error draining NATS connection", "err", err)
+			errs = append(errs, err)
+		}
+	}
+	if t.stopHealth != nil {
+		t.stopHealth()
+		select {
+		case <-t.healthDone:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("health check loop did not stop: %w", ctx.Err()))
+		}
+	}
+
+	// The tracer provider goes first so spans ended during shutdown still reach Sentry
+	if t.tracerProvider != nil {
+		if err := t.tracerProvider.Shutdown(ctx); err != nil {
+			logger.Error("Error shutting down tracer provider", "err", err)
+			errs = append(errs, fmt.Errorf("tracer provider shutdown: %w", err))
+		}
+	}
+	if t.meterProvider != nil {
+		if err := t.meterProvider.Shutdown(ctx); err != nil {
+			logger.Error("Error shutting down meter provider", "err", err)
+			errs = append(errs, fmt.Errorf("meter provider shutdown: %w", err))
+		}
+	}
+	if t.loggerProvider != nil {
+		if err := t.loggerProvider.Shutdown(ctx); err != nil {
+			logger.Error("Error shutting down logger provider", "err", err)
+			errs = append(errs, fmt.Errorf("logger provider shutdown: %w", err))
+		}
 	}
 
-	tp, err := initTelemetry(serviceName, environment, opts...)
+	if t.sentryStarted {
+		if !sentry.FlushWithContext(ctx) {
+			logger.Error("Error flushing Sentry", "err", ctx.Err())
+			errs = append(errs, fmt.Errorf("sentry flush did not complete: %w", ctx.Err()))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// drainNATS drains t.nc and waits until the connection is closed or ctx is done.
+func (t *Telemetry) drainNATS(ctx context.Context) error {
+	if err := t.nc.Drain(); err != nil && !errors.Is(err, nats.ErrConnectionClosed) {
+		return fmt.Errorf("nats drain: %w", err)
+	}
+	select {
+	case <-t.natsClosed:
+		return nil
+	case <-ctx.Done():
+		t.nc.Close()
+		return fmt.Errorf("nats drain did not complete: %w", ctx.Err())
+	}
+}
+
+// ShutdownFunc stops all telemetry started by Init. See Telemetry.Shutdown.
+type ShutdownFunc func(ctx context.Context) error
+
+// Init initializes all telemetry, installs it as the default and returns a shutdown
+// function to defer in main. It is a shorthand for New followed by SetDefault.
+//
+//	shutdown, err := telemetry.Init("my-service", "production", opts...)
+//	if err != nil {
+//	    return err
+//	}
+//	defer func() {
+//	    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	    defer cancel()
+//	    if err := shutdown(ctx); err != nil {
+//	        slog.Error("telemetry shutdown failed", "err", err)
+//	    }
+//	}()
+func Init(serviceName string, environment string, opts ...Option) (ShutdownFunc, error) {
+	t, err := New(serviceName, environment, opts...)
 	if err != nil {
 		return nil, err
 	}
-	if cfg.TraceEnabled && cfg.SentryEnabled {
-		return func() {
-			sentry.Flush(2 * time.Second)
-			if err := tp.Shutdown(context.Background()); err != nil {
-				slog.Error("Error shutting down tracer provider", "err", err)
-			}
-		}, nil
-	} else if cfg.SentryEnabled {
-		return func() {
-			sentry.Flush(2 * time.Second)
-		}, nil
-	}
-	return func() {}, nil
+	SetDefault(t)
+	return t.Shutdown, nil
 }
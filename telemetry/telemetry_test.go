@@ -1,15 +1,34 @@
 package telemetry
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
 	"log/slog"
+	"net"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/getsentry/sentry-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	lognoop "go.opentelemetry.io/otel/log/noop"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
+// useDefault installs an instance with cfg as the default for the duration of the test.
+func useDefault(t *testing.T, cfg Config) *Telemetry {
+	t.Helper()
+	tel := newTelemetry(cfg)
+	prev := defaultTelemetry.Swap(tel)
+	t.Cleanup(func() { defaultTelemetry.Store(prev) })
+	return tel
+}
+
 func TestInit_MinimalConfiguration(t *testing.T) {
 	// Test basic initialization with no optional components
 	shutdown, err := Init("test-service", "development")
@@ -18,7 +37,46 @@ func TestInit_MinimalConfiguration(t *testing.T) {
 	assert.NotNil(t, shutdown)
 
 	// Call shutdown function - should not panic
-	shutdown()
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestNew_DoesNotReplaceGlobals(t *testing.T) {
+	before := slog.Default()
+
+	var buf bytes.Buffer
+	tel, err := New("new-test", "test", WithSlog(SlogOutput(&buf), SlogFormat(LogFormatJSON)))
+	require.NoError(t, err)
+	defer func() { _ = tel.Shutdown(context.Background()) }()
+
+	assert.Same(t, before, slog.Default())
+	assert.NotSame(t, tel, Default())
+
+	tel.Logger().Info("from instance")
+	assert.Contains(t, buf.String(), `"msg":"from instance"`)
+}
+
+func TestNew_TwoConfigurations(t *testing.T) {
+	first, err := New("first-service", "test", WithMySQL(MySQLDSN("first-dsn")))
+	require.NoError(t, err)
+	defer func() { _ = first.Shutdown(context.Background()) }()
+	second, err := New("second-service", "staging")
+	require.NoError(t, err)
+	defer func() { _ = second.Shutdown(context.Background()) }()
+
+	assert.Equal(t, "first-service", first.cfg.ServiceName)
+	assert.True(t, first.cfg.MysqlEnabled)
+	assert.Equal(t, "second-service", second.cfg.ServiceName)
+	assert.False(t, second.cfg.MysqlEnabled)
+	assert.Nil(t, second.NATSConn())
+}
+
+func TestInit_InstallsDefault(t *testing.T) {
+	shutdown, err := Init("default-test", "test", WithSlog())
+	require.NoError(t, err)
+	defer func() { _ = shutdown(context.Background()) }()
+
+	assert.Equal(t, "default-test", Default().cfg.ServiceName)
+	assert.Same(t, Default().Logger(), slog.Default())
 }
 
 func TestInit_WithSlog(t *testing.T) {
@@ -32,10 +90,10 @@ func TestInit_WithSlog(t *testing.T) {
 	assert.NotNil(t, shutdown)
 
 	// Verify slog is configured
-	assert.True(t, TelemetryConfig.SlogEnabled)
-	assert.Equal(t, slog.LevelDebug, TelemetryConfig.SlogConfig.logLevel)
+	assert.True(t, Default().cfg.SlogEnabled)
+	assert.Equal(t, slog.LevelDebug, Default().cfg.SlogConfig.logLevel)
 
-	shutdown()
+	assert.NoError(t, shutdown(context.Background()))
 }
 
 func TestInit_WithSentry_MissingDSN(t *testing.T) {
@@ -67,7 +125,7 @@ func TestInit_WithSentry_ValidDSN(t *testing.T) {
 	// Use a test DSN format (not a real endpoint)
 	testDSN := "https://test@o123456.ingest.us.sentry.io/123456"
 
-	shutdown, err := Init(
+	tel, err := New(
 		"test-service",
 		"test",
 		WithSentry(
@@ -76,18 +134,14 @@ func TestInit_WithSentry_ValidDSN(t *testing.T) {
 			SentryRelease("v1.0.0"),
 		),
 	)
-
-	// This might fail or succeed depending on network, but we can check config was set
-	if err == nil {
-		assert.NotNil(t, shutdown)
-		shutdown()
-	}
+	require.NoError(t, err)
+	defer func() { _ = tel.Shutdown(context.Background()) }()
 
 	// Verify configuration was set
-	assert.True(t, TelemetryConfig.SentryEnabled)
-	assert.Equal(t, testDSN, TelemetryConfig.SentryConfig.DSN)
-	assert.Equal(t, "test", TelemetryConfig.SentryConfig.Environment)
-	assert.Equal(t, "v1.0.0", TelemetryConfig.SentryConfig.Release)
+	assert.True(t, tel.cfg.SentryEnabled)
+	assert.Equal(t, testDSN, tel.cfg.SentryConfig.DSN)
+	assert.Equal(t, "test", tel.cfg.SentryConfig.Environment)
+	assert.Equal(t, "v1.0.0", tel.cfg.SentryConfig.Release)
 }
 
 func TestInit_WithTrace_MissingExporterURL(t *testing.T) {
@@ -103,6 +157,47 @@ func TestInit_WithTrace_MissingExporterURL(t *testing.T) {
 	assert.Contains(t, err.Error(), "OpenTelemetry Exporter URL is required")
 }
 
+func TestNew_WithTrace_SpanProcessorOnly(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tel, err := New("test-service", "test", WithTrace(TraceSpanProcessor(sdktrace.NewSimpleSpanProcessor(exporter))))
+	require.NoError(t, err)
+	defer func() { _ = tel.Shutdown(context.Background()) }()
+
+	_, span := tel.TracerProvider().Tracer("test").Start(context.Background(), "in-memory")
+	span.End()
+
+	require.Len(t, exporter.GetSpans(), 1)
+	assert.Equal(t, "in-memory", exporter.GetSpans()[0].Name)
+}
+
+func TestNew_WithSlog_Handler(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	tel, err := New("test-service", "test", WithSlog(SlogHandler(handler), SlogLogLevel(slog.LevelWarn)))
+	require.NoError(t, err)
+	defer func() { _ = tel.Shutdown(context.Background()) }()
+
+	tel.Logger().Info("dropped by level")
+	tel.Logger().Warn("kept")
+
+	assert.NotContains(t, buf.String(), "dropped by level")
+	assert.Contains(t, buf.String(), `"msg":"kept"`)
+}
+
+func TestNew_WithSentry_ClientOptions(t *testing.T) {
+	prev := sentry.CurrentHub().Client()
+	defer sentry.CurrentHub().BindClient(prev)
+
+	tel, err := New("test-service", "test", WithSentry(
+		SentryDSN("https://public@example.com/1"),
+		SentryClientOptions(func(opts *sentry.ClientOptions) { opts.ServerName = "from-option" }),
+	))
+	require.NoError(t, err)
+	defer func() { _ = tel.Shutdown(context.Background()) }()
+
+	assert.Equal(t, "from-option", sentry.CurrentHub().Client().Options().ServerName)
+}
+
 func TestInit_WithTrace_InvalidExporterURL(t *testing.T) {
 	shutdown, err := Init(
 		"test-service",
@@ -115,10 +210,10 @@ func TestInit_WithTrace_InvalidExporterURL(t *testing.T) {
 	// Let's just verify that the configuration was set correctly
 	if err == nil {
 		assert.NotNil(t, shutdown)
-		shutdown()
+		assert.NoError(t, shutdown(context.Background()))
 		// Configuration should still be set even if connection works
-		assert.True(t, TelemetryConfig.TraceEnabled)
-		assert.Equal(t, "http://127.0.0.1:9999", TelemetryConfig.TraceConfig.ExporterURL)
+		assert.True(t, Default().cfg.TraceEnabled)
+		assert.Equal(t, "http://127.0.0.1:9999", Default().cfg.TraceConfig.ExporterURL)
 	} else {
 		assert.Nil(t, shutdown)
 	}
@@ -133,10 +228,10 @@ func TestInit_WithMySQL_MissingDSN(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.NotNil(t, shutdown)
-	assert.True(t, TelemetryConfig.MysqlEnabled)
-	assert.Empty(t, TelemetryConfig.MysqlConfig.DSN)
+	assert.True(t, Default().cfg.MysqlEnabled)
+	assert.Empty(t, Default().cfg.MysqlConfig.DSN)
 
-	shutdown()
+	assert.NoError(t, shutdown(context.Background()))
 }
 
 func TestInit_WithNATS_MissingURL(t *testing.T) {
@@ -169,9 +264,7 @@ func TestInit_ComplexConfiguration(t *testing.T) {
 	// Test configuration with multiple components (that should fail gracefully)
 	testDSN := "https://test@o123456.ingest.us.sentry.io/123456"
 
-	shutdown, err := Init(
-		"complex-test-service",
-		"production",
+	opts := []Option{
 		WithSlog(SlogLogLevel(slog.LevelInfo)),
 		WithSentry(
 			SentryDSN(testDSN),
@@ -181,7 +274,8 @@ func TestInit_ComplexConfiguration(t *testing.T) {
 		WithMySQL(MySQLDSN("user:pass@tcp(127.0.0.1:9998)/testdb")),
 		WithNATS(NATSURL("nats://127.0.0.1:9999")),
 		WithTrace(TraceExporterURL("localhost:4317")),
-	)
+	}
+	shutdown, err := Init("complex-test-service", "production", opts...)
 
 	// This will likely fail due to missing services, but we can check configuration
 	if err != nil {
@@ -189,16 +283,18 @@ func TestInit_ComplexConfiguration(t *testing.T) {
 		t.Logf("Expected error due to missing services: %v", err)
 	} else {
 		assert.NotNil(t, shutdown)
-		shutdown()
+		assert.NoError(t, shutdown(context.Background()))
 	}
 
 	// Verify all configurations were set
-	assert.True(t, TelemetryConfig.SlogEnabled)
-	assert.True(t, TelemetryConfig.SentryEnabled)
-	assert.True(t, TelemetryConfig.MysqlEnabled)
-	assert.True(t, TelemetryConfig.NatsEnabled)
-	assert.True(t, TelemetryConfig.TraceEnabled)
-	assert.Equal(t, "complex-test-service", TelemetryConfig.ServiceName)
+	cfg, err := newConfig("complex-test-service", "production", opts...)
+	require.NoError(t, err)
+	assert.True(t, cfg.SlogEnabled)
+	assert.True(t, cfg.SentryEnabled)
+	assert.True(t, cfg.MysqlEnabled)
+	assert.True(t, cfg.NatsEnabled)
+	assert.True(t, cfg.TraceEnabled)
+	assert.Equal(t, "complex-test-service", cfg.ServiceName)
 }
 
 func TestShutdownFunc(t *testing.T) {
@@ -208,23 +304,31 @@ func TestShutdownFunc(t *testing.T) {
 	assert.NotNil(t, shutdown)
 
 	// Calling shutdown multiple times should not panic
-	shutdown()
-	shutdown()
-	shutdown()
+	assert.NoError(t, shutdown(context.Background()))
+	assert.NoError(t, shutdown(context.Background()))
+	assert.NoError(t, shutdown(context.Background()))
 }
 
 func TestConfig_OptionFunctions(t *testing.T) {
 	tests := []struct {
 		name    string
 		option  Option
-		checkFn func(*config)
+		checkFn func(*Config)
 	}{
 		{
-			name:   "WithSlog sets slog config",
-			option: WithSlog(SlogLogLevel(slog.LevelWarn)),
-			checkFn: func(cfg *config) {
+			name: "WithSlog sets slog config",
+			option: WithSlog(
+				SlogLogLevel(slog.LevelWarn),
+				SlogFormat(LogFormatJSON),
+				SlogOutput(os.Stderr),
+				SlogAddSource(),
+			),
+			checkFn: func(cfg *Config) {
 				assert.True(t, cfg.SlogEnabled)
 				assert.Equal(t, slog.LevelWarn, cfg.SlogConfig.logLevel)
+				assert.Equal(t, LogFormatJSON, cfg.SlogConfig.format)
+				assert.Equal(t, os.Stderr, cfg.SlogConfig.output)
+				assert.True(t, cfg.SlogConfig.addSource)
 			},
 		},
 		{
@@ -234,7 +338,7 @@ func TestConfig_OptionFunctions(t *testing.T) {
 				SentryEnvironment("test-env"),
 				SentryRelease("test-release"),
 			),
-			checkFn: func(cfg *config) {
+			checkFn: func(cfg *Config) {
 				assert.True(t, cfg.SentryEnabled)
 				assert.Equal(t, "test-dsn", cfg.SentryConfig.DSN)
 				assert.Equal(t, "test-env", cfg.SentryConfig.Environment)
@@ -242,17 +346,39 @@ func TestConfig_OptionFunctions(t *testing.T) {
 			},
 		},
 		{
-			name:   "WithTrace sets trace config",
-			option: WithTrace(TraceExporterURL("test-url")),
-			checkFn: func(cfg *config) {
+			name: "WithTrace sets trace config",
+			option: WithTrace(
+				TraceExporterURL("test-url"),
+				TraceSampleRatio(0.25),
+				TraceParentBased(),
+				TraceSampleRule("GET /healthz", 0),
+			),
+			checkFn: func(cfg *Config) {
 				assert.True(t, cfg.TraceEnabled)
 				assert.Equal(t, "test-url", cfg.TraceConfig.ExporterURL)
+				assert.Equal(t, "TraceIDRatioBased{0.25}", cfg.TraceConfig.Sampler.Description())
+				assert.True(t, cfg.TraceConfig.ParentBased)
+				assert.Len(t, cfg.TraceConfig.SampleRules, 1)
+			},
+		},
+		{
+			name: "WithMetrics sets metrics config",
+			option: WithMetrics(
+				MetricsExporterURL("test-metrics-url"),
+				MetricsInterval(30*time.Second),
+				MetricsPrometheus(),
+			),
+			checkFn: func(cfg *Config) {
+				assert.True(t, cfg.MetricsEnabled)
+				assert.Equal(t, "test-metrics-url", cfg.MetricsConfig.ExporterURL)
+				assert.Equal(t, 30*time.Second, cfg.MetricsConfig.Interval)
+				assert.True(t, cfg.MetricsConfig.Prometheus)
 			},
 		},
 		{
 			name:   "WithMySQL sets mysql config",
 			option: WithMySQL(MySQLDSN("test-mysql-dsn")),
-			checkFn: func(cfg *config) {
+			checkFn: func(cfg *Config) {
 				assert.True(t, cfg.MysqlEnabled)
 				assert.Equal(t, "test-mysql-dsn", cfg.MysqlConfig.DSN)
 			},
@@ -260,7 +386,7 @@ func TestConfig_OptionFunctions(t *testing.T) {
 		{
 			name:   "WithNATS sets nats config",
 			option: WithNATS(NATSURL("test-nats-url")),
-			checkFn: func(cfg *config) {
+			checkFn: func(cfg *Config) {
 				assert.True(t, cfg.NatsEnabled)
 				assert.Equal(t, "test-nats-url", cfg.NatsConfig.URL)
 			},
@@ -269,13 +395,70 @@ func TestConfig_OptionFunctions(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(_ *testing.T) {
-			cfg := &config{}
+			cfg := &Config{}
 			tt.option(cfg)
 			tt.checkFn(cfg)
 		})
 	}
 }
 
+func TestInit_WithSlog_JSONOutput(t *testing.T) {
+	var buf bytes.Buffer
+	shutdown, err := Init(
+		"json-test",
+		"test",
+		WithSlog(
+			SlogFormat(LogFormatJSON),
+			SlogOutput(&buf),
+			SlogAddSource(),
+			SlogReplaceAttr(func(_ []string, a slog.Attr) slog.Attr {
+				if a.Key == "password" {
+					return slog.String("password", "***")
+				}
+				return a
+			}),
+		),
+	)
+	require.NoError(t, err)
+	defer func() { _ = shutdown(context.Background()) }()
+
+	tp := sdktrace.NewTracerProvider()
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "json-span")
+	defer span.End()
+
+	buf.Reset()
+	slog.Default().With("component", "test").InfoContext(ctx, "user login", "password", "secret")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "user login", entry["msg"])
+	assert.Equal(t, "test", entry["component"])
+	assert.Equal(t, "***", entry["password"])
+	assert.Equal(t, span.SpanContext().TraceID().String(), entry["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), entry["span_id"])
+	assert.Contains(t, entry, "source")
+}
+
+func TestInit_WithSlog_TextOutput(t *testing.T) {
+	var buf bytes.Buffer
+	shutdown, err := Init("text-test", "test", WithSlog(SlogOutput(&buf)))
+	require.NoError(t, err)
+	defer func() { _ = shutdown(context.Background()) }()
+
+	tp := sdktrace.NewTracerProvider()
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "text-span")
+	defer span.End()
+
+	buf.Reset()
+	slog.Default().WithGroup("request").InfoContext(ctx, "handled", "path", "/orders")
+
+	assert.Contains(t, buf.String(), "msg=handled")
+	assert.Contains(t, buf.String(), "request.path=/orders")
+	assert.Contains(t, buf.String(), span.SpanContext().TraceID().String())
+}
+
 // Test environment variable integration (if any)
 func TestInit_EnvironmentIntegration(t *testing.T) {
 	// Save original env vars
@@ -292,13 +475,12 @@ func TestInit_EnvironmentIntegration(t *testing.T) {
 		_ = os.Setenv("OTEL_EXPORTER_ENDPOINT", originalOtel)
 	}()
 
-	// This test demonstrates how the library would be used with environment variables
-	// but doesn't actually use them in the current implementation
+	// Without FromEnv, Init ignores environment variables
 
 	shutdown, err := Init("env-test", "test")
 	require.NoError(t, err)
 	assert.NotNil(t, shutdown)
-	shutdown()
+	assert.NoError(t, shutdown(context.Background()))
 }
 
 func TestInit_ConcurrentCalls(t *testing.T) {
@@ -311,7 +493,7 @@ func TestInit_ConcurrentCalls(t *testing.T) {
 			if shutdown != nil {
 				// Add small delay to test shutdown timing
 				time.Sleep(time.Millisecond * 10)
-				shutdown()
+				assert.NoError(t, shutdown(context.Background()))
 			}
 			results <- err
 		}(i)
@@ -326,3 +508,103 @@ func TestInit_ConcurrentCalls(t *testing.T) {
 		}
 	}
 }
+
+func TestShutdown_StopsProviders(t *testing.T) {
+	tel, err := New("shutdown-providers", "test", WithMetrics(MetricsPrometheus()))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, tel.Shutdown(ctx))
+
+	// The provider was shut down by the first call, so stopping it again fails
+	assert.Error(t, tel.meterProvider.Shutdown(context.Background()))
+	// Later calls return the first result instead of shutting down again
+	assert.NoError(t, tel.Shutdown(context.Background()))
+}
+
+func TestShutdown_StopsHealthLoop(t *testing.T) {
+	tel := newTelemetry(Config{})
+	healthCtx, stop := context.WithCancel(context.Background())
+	tel.stopHealth = stop
+	tel.healthDone = make(chan struct{})
+	go func() {
+		defer close(tel.healthDone)
+		<-healthCtx.Done()
+	}()
+
+	require.NoError(t, tel.Shutdown(context.Background()))
+	select {
+	case <-tel.healthDone:
+	default:
+		t.Fatal("health check loop still running after Shutdown")
+	}
+}
+
+func TestShutdown_DeadlineExceeded(t *testing.T) {
+	tel := newTelemetry(Config{})
+	_, stop := context.WithCancel(context.Background())
+	tel.stopHealth = stop
+	tel.healthDone = make(chan struct{}) // Never closed
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := tel.Shutdown(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// newFakeNATSServer speaks just enough of the NATS protocol for a client to connect,
+// subscribe and drain, and returns its URL.
+func newFakeNATSServer(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lis.Close() })
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				_, _ = io.WriteString(conn, `INFO {"server_id":"fake","version":"2.10.0","max_payload":1048576}`+"\r\n")
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					if scanner.Text() == "PING" {
+						_, _ = io.WriteString(conn, "PONG\r\n")
+					}
+				}
+			}()
+		}
+	}()
+	return "nats://" + lis.Addr().String()
+}
+
+func TestStart_FailureStopsStartedComponents(t *testing.T) {
+	collector, _, _ := newFakeCollector(t)
+
+	cfg, err := newConfig("start-failure", "test",
+		WithNATS(NATSURL(newFakeNATSServer(t))),
+		WithLogExport(LogExportURL(collector)),
+		WithSentry(), // Fails after NATS and log export have started: no DSN
+	)
+	require.NoError(t, err)
+	tel := newTelemetry(*cfg)
+
+	err = tel.start()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sentry DSN is required")
+
+	require.NotNil(t, tel.nc, "NATS was started before the failing step")
+	assert.True(t, tel.nc.IsClosed())
+	select {
+	case <-tel.healthDone:
+	default:
+		t.Fatal("health check loop still running after a failed start")
+	}
+	require.NotNil(t, tel.loggerProvider, "log export was started before the failing step")
+	assert.IsType(t, lognoop.Logger{}, tel.loggerProvider.Logger("after-failure"))
+}
@@ -0,0 +1,122 @@
+package telemetrytest
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogRecord is a record logged through the kit's logger.
+type LogRecord struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	// Attrs holds the resolved attributes, including those added with Logger.With. Keys in
+	// groups are prefixed with the group names joined by dots, e.g. "request.method".
+	Attrs map[string]slog.Value
+}
+
+// logRecorder is a slog handler that keeps the records. Handlers derived with WithAttrs
+// and WithGroup share the records of the handler they came from.
+type logRecorder struct {
+	mu      *sync.Mutex
+	records *[]LogRecord
+	attrs   map[string]slog.Value
+	prefix  string
+}
+
+func newLogRecorder() *logRecorder {
+	return &logRecorder{mu: &sync.Mutex{}, records: &[]LogRecord{}}
+}
+
+// Enabled accepts every level; the telemetry logger applies the configured level before.
+func (h *logRecorder) Enabled(_ context.Context, _ slog.Level) bool {
+	return true
+}
+
+func (h *logRecorder) Handle(_ context.Context, r slog.Record) error {
+	record := LogRecord{
+		Time:    r.Time,
+		Level:   r.Level,
+		Message: r.Message,
+		Attrs:   make(map[string]slog.Value, len(h.attrs)+r.NumAttrs()),
+	}
+	maps.Copy(record.Attrs, h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(record.Attrs, h.prefix, a)
+		return true
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.records = append(*h.records, record)
+	return nil
+}
+
+func (h *logRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = make(map[string]slog.Value, len(h.attrs)+len(attrs))
+	maps.Copy(h2.attrs, h.attrs)
+	for _, a := range attrs {
+		addAttr(h2.attrs, h.prefix, a)
+	}
+	return &h2
+}
+
+func (h *logRecorder) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// addAttr resolves a and stores it in attrs under prefix, flattening groups.
+func addAttr(attrs map[string]slog.Value, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		group := prefix
+		if a.Key != "" {
+			group += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			addAttr(attrs, group, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	attrs[prefix+a.Key] = a.Value
+}
+
+// Logs returns the records logged so far, in order.
+func (k *Kit) Logs() []LogRecord {
+	k.logs.mu.Lock()
+	defer k.logs.mu.Unlock()
+	return slices.Clone(*k.logs.records)
+}
+
+// AssertLog fails the test immediately unless a record with message was logged, and
+// returns the first one.
+func (k *Kit) AssertLog(message string) LogRecord {
+	k.t.Helper()
+	records := k.Logs()
+	for _, record := range records {
+		if record.Message == message {
+			return record
+		}
+	}
+	parts := make([]string, 0, len(records))
+	for _, record := range records {
+		parts = append(parts, strconv.Quote(record.Message))
+	}
+	k.t.Fatalf("telemetrytest: no log record %q; records: %s", message, strings.Join(parts, ", "))
+	return LogRecord{}
+}
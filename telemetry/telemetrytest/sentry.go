@@ -0,0 +1,199 @@
+package telemetrytest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// sentryRecorder is a Sentry transport that keeps the events instead of sending them. It
+// also keeps the breadcrumbs and the errors the events were captured from.
+type sentryRecorder struct {
+	mu          sync.Mutex
+	events      []*sentry.Event
+	originals   map[sentry.EventID]error
+	breadcrumbs []*sentry.Breadcrumb
+}
+
+func newSentryRecorder() *sentryRecorder {
+	return &sentryRecorder{originals: map[sentry.EventID]error{}}
+}
+
+func (r *sentryRecorder) Flush(_ time.Duration) bool              { return true }
+func (r *sentryRecorder) FlushWithContext(_ context.Context) bool { return true }
+func (r *sentryRecorder) Configure(_ sentry.ClientOptions)        {}
+func (r *sentryRecorder) Close()                                  {}
+
+func (r *sentryRecorder) SendEvent(event *sentry.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// configure installs r as the transport and hooks the breadcrumb and event callbacks,
+// keeping any callbacks set by other options.
+func (r *sentryRecorder) configure(opts *sentry.ClientOptions) {
+	opts.Transport = r
+
+	beforeBreadcrumb := opts.BeforeBreadcrumb
+	opts.BeforeBreadcrumb = func(breadcrumb *sentry.Breadcrumb, hint *sentry.BreadcrumbHint) *sentry.Breadcrumb {
+		if beforeBreadcrumb != nil {
+			if breadcrumb = beforeBreadcrumb(breadcrumb, hint); breadcrumb == nil {
+				return nil
+			}
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.breadcrumbs = append(r.breadcrumbs, breadcrumb)
+		return breadcrumb
+	}
+
+	beforeSend := opts.BeforeSend
+	opts.BeforeSend = func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+		if beforeSend != nil {
+			if event = beforeSend(event, hint); event == nil {
+				return nil
+			}
+		}
+		if err, ok := hint.OriginalException.(error); ok {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.originals[event.EventID] = err
+		}
+		return event
+	}
+}
+
+// original returns the error event was captured from, or nil.
+func (r *sentryRecorder) original(event *sentry.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.originals[event.EventID]
+}
+
+// SentryEvents returns the error and message events sent to Sentry so far, without transactions.
+func (k *Kit) SentryEvents() []*sentry.Event {
+	k.sentry.mu.Lock()
+	defer k.sentry.mu.Unlock()
+	var events []*sentry.Event
+	for _, event := range k.sentry.events {
+		if event.Type != "transaction" {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Breadcrumbs returns the breadcrumbs added so far on any hub, in order.
+func (k *Kit) Breadcrumbs() []*sentry.Breadcrumb {
+	k.sentry.mu.Lock()
+	defer k.sentry.mu.Unlock()
+	return slices.Clone(k.sentry.breadcrumbs)
+}
+
+// AssertCapturedError fails the test immediately unless an event was sent to Sentry for an
+// error matching err with errors.Is, and returns the first such event. A panic reported
+// by telemetry.Recover matches the error it was raised with.
+func (k *Kit) AssertCapturedError(err error) *sentry.Event {
+	k.t.Helper()
+	events := k.SentryEvents()
+	for _, event := range events {
+		if original := k.sentry.original(event); original != nil && errors.Is(original, err) {
+			return event
+		}
+	}
+	k.t.Fatalf("telemetrytest: no Sentry event for error %q; events: %s", err, describeEvents(events))
+	return nil
+}
+
+// AssertCapturedMessage fails the test immediately unless message was sent to Sentry with
+// telemetry.CaptureMessage, and returns the first such event.
+func (k *Kit) AssertCapturedMessage(message string) *sentry.Event {
+	k.t.Helper()
+	events := k.SentryEvents()
+	for _, event := range events {
+		if event.Message == message {
+			return event
+		}
+	}
+	k.t.Fatalf("telemetrytest: no Sentry event for message %q; events: %s", message, describeEvents(events))
+	return nil
+}
+
+// AssertNoSentryEvents fails the test if any error or message event was sent to Sentry,
+// e.g. to check that an expected error was classified as log-only or ignored.
+func (k *Kit) AssertNoSentryEvents() {
+	k.t.Helper()
+	if events := k.SentryEvents(); len(events) > 0 {
+		k.t.Errorf("telemetrytest: expected no Sentry events, got %s", describeEvents(events))
+	}
+}
+
+// AssertBreadcrumb fails the test immediately unless a breadcrumb of category was added,
+// and returns the first one.
+func (k *Kit) AssertBreadcrumb(category string) *sentry.Breadcrumb {
+	k.t.Helper()
+	breadcrumbs := k.Breadcrumbs()
+	for _, breadcrumb := range breadcrumbs {
+		if breadcrumb.Category == category {
+			return breadcrumb
+		}
+	}
+	k.t.Fatalf("telemetrytest: no breadcrumb of category %q; categories: %s", category, describeBreadcrumbs(breadcrumbs))
+	return nil
+}
+
+// AssertBreadcrumbNoField fails the test if a breadcrumb of category carries field in its
+// data, e.g. to check that a payload or a secret doesn't reach Sentry. It also fails when
+// no breadcrumb of category was added, since the check would be meaningless.
+func (k *Kit) AssertBreadcrumbNoField(category, field string) {
+	k.t.Helper()
+	breadcrumbs := k.Breadcrumbs()
+	found := false
+	for _, breadcrumb := range breadcrumbs {
+		if breadcrumb.Category != category {
+			continue
+		}
+		found = true
+		if value, ok := breadcrumb.Data[field]; ok {
+			k.t.Errorf("telemetrytest: breadcrumb %q %q has field %q = %v", category, breadcrumb.Message, field, value)
+		}
+	}
+	if !found {
+		k.t.Errorf("telemetrytest: no breadcrumb of category %q; categories: %s", category, describeBreadcrumbs(breadcrumbs))
+	}
+}
+
+// describeEvents lists the messages or exception values of events for failure messages.
+func describeEvents(events []*sentry.Event) string {
+	if len(events) == 0 {
+		return "none"
+	}
+	parts := make([]string, 0, len(events))
+	for _, event := range events {
+		if len(event.Exception) > 0 {
+			parts = append(parts, "error "+strconv.Quote(event.Exception[len(event.Exception)-1].Value))
+		} else {
+			parts = append(parts, "message "+strconv.Quote(event.Message))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeBreadcrumbs lists the categories of breadcrumbs for failure messages.
+func describeBreadcrumbs(breadcrumbs []*sentry.Breadcrumb) string {
+	if len(breadcrumbs) == 0 {
+		return "none"
+	}
+	parts := make([]string, 0, len(breadcrumbs))
+	for _, breadcrumb := range breadcrumbs {
+		parts = append(parts, strconv.Quote(breadcrumb.Category))
+	}
+	return strings.Join(parts, ", ")
+}
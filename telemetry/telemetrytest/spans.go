@@ -0,0 +1,55 @@
+package telemetrytest
+
+import (
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Spans returns the spans ended so far, in the order they ended. Spans still running
+// are not included.
+func (k *Kit) Spans() tracetest.SpanStubs {
+	return k.spans.GetSpans()
+}
+
+// AssertSpan fails the test immediately unless a span named name has ended, and returns
+// the first one.
+func (k *Kit) AssertSpan(name string) tracetest.SpanStub {
+	k.t.Helper()
+	spans := k.Spans()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	k.t.Fatalf("telemetrytest: no span named %q; spans: %s", name, describeSpans(spans))
+	return tracetest.SpanStub{}
+}
+
+// AssertSpanParent fails the test unless the span named name is a child of the span named
+// parent, e.g. "nats.receive.orders" of "nats.publish.orders" when the trace context
+// travels in the message headers. The parent may have been received as a remote span
+// context. It returns the child.
+func (k *Kit) AssertSpanParent(name, parent string) tracetest.SpanStub {
+	k.t.Helper()
+	child := k.AssertSpan(name)
+	want := k.AssertSpan(parent).SpanContext
+	if child.Parent.TraceID() != want.TraceID() || child.Parent.SpanID() != want.SpanID() {
+		k.t.Errorf("telemetrytest: span %q has parent %s/%s, want %q %s/%s", name,
+			child.Parent.TraceID(), child.Parent.SpanID(), parent, want.TraceID(), want.SpanID())
+	}
+	return child
+}
+
+// describeSpans lists the names of spans for failure messages.
+func describeSpans(spans tracetest.SpanStubs) string {
+	if len(spans) == 0 {
+		return "none"
+	}
+	parts := make([]string, 0, len(spans))
+	for _, span := range spans {
+		parts = append(parts, strconv.Quote(span.Name))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,128 @@
+// Package telemetrytest provides an in-memory telemetry setup for asserting the spans,
+// Sentry events, breadcrumbs and log records a test produces.
+package telemetrytest
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/TMSLabs/go-tooling/telemetry"
+	"github.com/getsentry/sentry-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/log/global"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// sentryDSN is a syntactically valid DSN. Nothing is sent to it, the kit's transport keeps the events.
+const sentryDSN = "https://public@example.com/1"
+
+// Kit is a telemetry instance whose spans, Sentry events, breadcrumbs and log records stay
+// in memory. It is installed as the default instance, so the package-level telemetry
+// functions and the httphelper and natshelper wrappers report to it.
+type Kit struct {
+	// Telemetry is the instance installed by New.
+	Telemetry *telemetry.Telemetry
+
+	t      testing.TB
+	spans  *tracetest.InMemoryExporter
+	sentry *sentryRecorder
+	logs   *logRecorder
+}
+
+// New starts a telemetry instance with slog, Sentry and tracing enabled and recording in
+// memory, and installs it with telemetry.SetDefault. opts are applied first, so a test can
+// enable more features, e.g. telemetry.WithCaptureLimit or telemetry.WithRedaction, or set
+// a trace sampler. The previous default instance and the slog, OpenTelemetry and Sentry
+// globals are restored when the test ends.
+//
+// Because it replaces globals, tests using the kit must not run in parallel:
+//
+//	func TestOrders(t *testing.T) {
+//	    kit := telemetrytest.New(t, telemetry.WithRedaction())
+//	    // ... exercise the code under test ...
+//	    kit.AssertSpanParent("nats.receive.orders", "nats.publish.orders")
+//	    kit.AssertCapturedError(ErrOutOfStock)
+//	    kit.AssertBreadcrumbNoField("nats.publish", "card_number")
+//	}
+func New(t testing.TB, opts ...telemetry.Option) *Kit {
+	t.Helper()
+	k := &Kit{
+		t:      t,
+		spans:  tracetest.NewInMemoryExporter(),
+		sentry: newSentryRecorder(),
+		logs:   newLogRecorder(),
+	}
+
+	restore := saveGlobals()
+	tel, err := telemetry.New("telemetrytest", "test", append(slices.Clip(opts), k.option())...)
+	if err != nil {
+		restore()
+		t.Fatalf("telemetrytest: starting telemetry: %v", err)
+	}
+	telemetry.SetDefault(tel)
+	k.Telemetry = tel
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tel.Shutdown(ctx); err != nil {
+			t.Errorf("telemetrytest: shutting down telemetry: %v", err)
+		}
+		restore()
+	})
+	return k
+}
+
+// option enables slog, Sentry and tracing on top of whatever opts configured, with the
+// kit's handler, transport and exporter.
+func (k *Kit) option() telemetry.Option {
+	return func(cfg *telemetry.Config) {
+		cfg.SlogEnabled = true
+		telemetry.SlogHandler(k.logs)(&cfg.SlogConfig)
+
+		cfg.SentryEnabled = true
+		if cfg.SentryConfig.DSN == "" {
+			telemetry.SentryDSN(sentryDSN)(&cfg.SentryConfig)
+		}
+		telemetry.SentryClientOptions(k.sentry.configure)(&cfg.SentryConfig)
+
+		cfg.TraceEnabled = true
+		telemetry.TraceSpanProcessor(sdktrace.NewSimpleSpanProcessor(k.spans))(&cfg.TraceConfig)
+	}
+}
+
+// saveGlobals records the globals SetDefault and sentry.Init replace and returns a
+// function that puts them back.
+func saveGlobals() func() {
+	prevDefault := telemetry.Default()
+	prevLogger := slog.Default()
+	prevTracerProvider := otel.GetTracerProvider()
+	prevPropagator := otel.GetTextMapPropagator()
+	prevMeterProvider := otel.GetMeterProvider()
+	prevLoggerProvider := global.GetLoggerProvider()
+	hub := sentry.CurrentHub()
+	prevClient := hub.Client()
+
+	return func() {
+		telemetry.SetDefault(prevDefault)
+		slog.SetDefault(prevLogger)
+		// Setting a global to its current value logs an OpenTelemetry error
+		if otel.GetTracerProvider() != prevTracerProvider {
+			otel.SetTracerProvider(prevTracerProvider)
+		}
+		if otel.GetTextMapPropagator() != prevPropagator {
+			otel.SetTextMapPropagator(prevPropagator)
+		}
+		if otel.GetMeterProvider() != prevMeterProvider {
+			otel.SetMeterProvider(prevMeterProvider)
+		}
+		if global.GetLoggerProvider() != prevLoggerProvider {
+			global.SetLoggerProvider(prevLoggerProvider)
+		}
+		hub.BindClient(prevClient)
+	}
+}
@@ -0,0 +1,214 @@
+package telemetrytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TMSLabs/go-tooling/httphelper"
+	"github.com/TMSLabs/go-tooling/telemetry"
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+var errOutOfStock = errors.New("out of stock")
+
+func TestKit_HTTPRoundTrip(t *testing.T) {
+	kit := New(t)
+
+	server := httptest.NewServer(httphelper.HTTPHandler(func(ctx context.Context, w http.ResponseWriter, _ *http.Request) {
+		err := telemetry.Trace(ctx, "orders.reserve", func(_ context.Context) error {
+			return fmt.Errorf("reserving order: %w", errOutOfStock)
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusConflict)
+		}
+	}, "orders.receive"))
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/orders?token=abc123", nil)
+	require.NoError(t, err)
+	resp, err := httphelper.HTTPDo(context.Background(), server.Client(), req, "orders.send")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	server.Close() // Waits for the handler, so its spans have ended
+
+	kit.AssertSpanParent("orders.receive", "orders.send")
+	reserve := kit.AssertSpanParent("orders.reserve", "orders.receive")
+
+	event := kit.AssertCapturedError(errOutOfStock)
+	assert.Equal(t, sentry.LevelError, event.Level)
+
+	breadcrumb := kit.AssertBreadcrumb("http.request")
+	assert.Contains(t, breadcrumb.Data["url"], "token=[REDACTED]")
+	kit.AssertBreadcrumbNoField("http.receive", "body")
+
+	record := kit.AssertLog("Error captured")
+	assert.Equal(t, slog.LevelError, record.Level)
+	assert.Equal(t, "orders.reserve failed", record.Attrs["message"].String())
+	assert.Equal(t, reserve.SpanContext.TraceID().String(), record.Attrs["trace_id"].String())
+}
+
+func TestKit_Options(t *testing.T) {
+	kit := New(t, telemetry.WithCaptureLimit(telemetry.CaptureLimitRate(1, time.Minute)))
+
+	ctx := context.Background()
+	telemetry.CaptureError(ctx, context.Canceled, "request aborted")
+	kit.AssertNoSentryEvents()
+	assert.Equal(t, "context.canceled", kit.AssertLog("Error captured").Attrs["error_class"].String())
+
+	telemetry.CaptureMessage(ctx, "fallback region in use")
+	telemetry.CaptureMessage(ctx, "fallback region in use")
+	kit.AssertCapturedMessage("fallback region in use")
+	assert.Len(t, kit.SentryEvents(), 1, "the limiter drops the second message")
+}
+
+func TestKit_Panic(t *testing.T) {
+	kit := New(t)
+
+	err := telemetry.Trace(context.Background(), "orders.sync", func(_ context.Context) error {
+		panic(errOutOfStock)
+	})
+	require.ErrorIs(t, err, errOutOfStock)
+
+	event := kit.AssertCapturedError(errOutOfStock)
+	assert.Equal(t, sentry.LevelFatal, event.Level)
+	kit.AssertSpan("orders.sync")
+}
+
+func TestKit_LogAttrs(t *testing.T) {
+	kit := New(t)
+
+	slog.Default().With("component", "billing").WithGroup("request").Info("handled", "method", "GET", slog.Group("user", "id", 7))
+
+	record := kit.AssertLog("handled")
+	assert.Equal(t, "billing", record.Attrs["component"].String())
+	assert.Equal(t, "GET", record.Attrs["request.method"].String())
+	assert.Equal(t, int64(7), record.Attrs["request.user.id"].Int64())
+}
+
+func TestNew_RestoresGlobals(t *testing.T) {
+	prevDefault := telemetry.Default()
+	prevLogger := slog.Default()
+	prevTracerProvider := otel.GetTracerProvider()
+	prevClient := sentry.CurrentHub().Client()
+
+	t.Run("kit", func(t *testing.T) {
+		kit := New(t)
+		assert.Same(t, kit.Telemetry, telemetry.Default())
+		assert.NotSame(t, prevLogger, slog.Default())
+	})
+
+	assert.Same(t, prevDefault, telemetry.Default())
+	assert.Same(t, prevLogger, slog.Default())
+	assert.Equal(t, prevTracerProvider, otel.GetTracerProvider())
+	assert.Same(t, prevClient, sentry.CurrentHub().Client())
+}
+
+// fakeT records the failures of the kit's assertions. Fatalf stops the goroutine like
+// testing.T does.
+type fakeT struct {
+	testing.TB
+	mu       sync.Mutex
+	failures []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatalf(format string, args ...any) {
+	f.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// failures runs fn with the kit reporting to a fakeT and returns the failures.
+func failures(kit *Kit, fn func()) []string {
+	fake := &fakeT{}
+	realT := kit.t
+	kit.t = fake
+	defer func() { kit.t = realT }()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	<-done
+	return fake.failures
+}
+
+func TestKit_AssertionFailures(t *testing.T) {
+	kit := New(t)
+	ctx := telemetry.ContextWithHub(context.Background())
+	telemetry.AddBreadcrumb(ctx, &sentry.Breadcrumb{
+		Category: "nats.publish",
+		Data:     map[string]any{"subject": "orders", "data": `{"id":1}`},
+	})
+	_, span := otel.Tracer("test").Start(ctx, "nats.publish.orders")
+	span.End()
+
+	tests := []struct {
+		name string
+		fn   func()
+		want string
+	}{
+		{
+			name: "missing span",
+			fn:   func() { kit.AssertSpan("nats.receive.orders") },
+			want: `telemetrytest: no span named "nats.receive.orders"; spans: "nats.publish.orders"`,
+		},
+		{
+			name: "wrong parent",
+			fn:   func() { kit.AssertSpanParent("nats.publish.orders", "nats.publish.orders") },
+			want: `telemetrytest: span "nats.publish.orders" has parent`,
+		},
+		{
+			name: "missing error",
+			fn:   func() { kit.AssertCapturedError(errOutOfStock) },
+			want: `telemetrytest: no Sentry event for error "out of stock"; events: none`,
+		},
+		{
+			name: "missing message",
+			fn:   func() { kit.AssertCapturedMessage("hello") },
+			want: `telemetrytest: no Sentry event for message "hello"; events: none`,
+		},
+		{
+			name: "breadcrumb field present",
+			fn:   func() { kit.AssertBreadcrumbNoField("nats.publish", "data") },
+			want: `telemetrytest: breadcrumb "nats.publish" "" has field "data" = {"id":1}`,
+		},
+		{
+			name: "no breadcrumb of category",
+			fn:   func() { kit.AssertBreadcrumbNoField("nats.receive", "data") },
+			want: `telemetrytest: no breadcrumb of category "nats.receive"; categories: "nats.publish"`,
+		},
+		{
+			name: "missing log",
+			fn:   func() { kit.AssertLog("never logged") },
+			want: `telemetrytest: no log record "never logged"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := failures(kit, tt.fn)
+			require.Len(t, got, 1)
+			assert.Contains(t, got[0], tt.want)
+		})
+	}
+
+	assert.Empty(t, failures(kit, func() { kit.AssertBreadcrumbNoField("nats.publish", "payload") }))
+}